@@ -0,0 +1,84 @@
+// gexpand stream graphite glob expressions expansions to stdout
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/msaf1980/go-matcher/expand"
+)
+
+type config struct {
+	limit int
+	depth int
+	count bool
+}
+
+func main() {
+	var cfg config
+
+	flag.IntVar(&cfg.limit, "n", -1, "max expanded results for each expression (-1 for unlimited)")
+	flag.IntVar(&cfg.depth, "depth", 0, "max expanded nodes (dot-separated), 0 for unlimited")
+	flag.BoolVar(&cfg.count, "c", false, "print only expanded results count (without limit)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [expression ...]\nRead expressions from stdin if not set\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var in io.Reader
+	if flag.NArg() > 0 {
+		in = strings.NewReader(strings.Join(flag.Args(), "\n"))
+	} else {
+		in = os.Stdin
+	}
+
+	if err := run(cfg, in, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg config, in io.Reader, out io.Writer) error {
+	w := bufio.NewWriter(out)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+	for scanner.Scan() {
+		expr := strings.TrimSpace(scanner.Text())
+		if expr == "" {
+			continue
+		}
+		it := expand.NewIterator(expr, cfg.depth)
+		if cfg.count {
+			n, overflow := it.Count()
+			if overflow {
+				w.WriteString("overflow")
+			} else {
+				fmt.Fprint(w, n)
+			}
+			w.WriteByte('\t')
+			w.WriteString(expr)
+			w.WriteByte('\n')
+			continue
+		}
+		for i := 0; cfg.limit < 0 || i < cfg.limit; i++ {
+			s, ok := it.Next()
+			if !ok {
+				break
+			}
+			w.WriteString(s)
+			if err := w.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_run(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config
+		in   string
+		want string
+	}{
+		{
+			name: "unlimited",
+			cfg:  config{limit: -1},
+			in:   "a{b,c}d\n\n[1-2]\n",
+			want: "abd\nacd\n1\n2\n",
+		},
+		{
+			name: "limit",
+			cfg:  config{limit: 3},
+			in:   "{a..}[0-9][0-9][0-9]\n",
+			want: "a..000\na..001\na..002\n",
+		},
+		{
+			name: "depth",
+			cfg:  config{limit: -1, depth: 1},
+			in:   "a{b,c}.[1-2]\n",
+			want: "ab.[1-2]\nac.[1-2]\n",
+		},
+		{
+			name: "count",
+			cfg:  config{count: true},
+			in:   "{a,b}[0-9][0-9][0-9]\n{a,b}[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]\n",
+			want: "2000\t{a,b}[0-9][0-9][0-9]\noverflow\t{a,b}[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := run(tt.cfg, strings.NewReader(tt.in), &out); err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// gmatch match metric paths (from stdin) against graphite globs/seriesByTag patterns
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/msaf1980/go-matcher/gtags"
+)
+
+func main() {
+	var (
+		cfg     config
+		dialect string
+		err     error
+	)
+
+	flag.StringVar(&cfg.patterns, "p", "", "patterns file (graphite globs or seriesByTag queries, one per line)")
+	flag.StringVar(&cfg.format, "format", formatTSV, "output format (tsv or json)")
+	flag.BoolVar(&cfg.globs, "globs", false, "print normalized patterns instead of patterns indexes")
+	flag.BoolVar(&cfg.first, "first", false, "print only first matched pattern (with lowest index)")
+	flag.BoolVar(&cfg.inverse, "v", false, "print only unmatched paths")
+	flag.BoolVar(&cfg.counts, "c", false, "print only counts summary")
+	flag.StringVar(&dialect, "dialect", gtags.DialectGraphiteClickHouse.String(), "seriesByTag dialect (graphite-clickhouse, graphite-web or prometheus) with optional +literal-eq, +name-gglob options")
+	flag.Parse()
+
+	if cfg.dialect, err = gtags.ParseDialect(dialect); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err = run(cfg, os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg config, in io.Reader, out, errOut io.Writer) error {
+	if cfg.patterns == "" {
+		return errPatternsNotSet
+	}
+	if cfg.format != formatTSV && cfg.format != formatJSON {
+		return errFormatInvalid{cfg.format}
+	}
+
+	m := newMatcher(cfg.dialect)
+	f, err := os.Open(cfg.patterns)
+	if err != nil {
+		return err
+	}
+	err = m.load(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	return m.run(cfg, in, out, errOut)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/msaf1980/go-matcher/gglob"
+	"github.com/msaf1980/go-matcher/glob"
+	"github.com/msaf1980/go-matcher/gtags"
+	"github.com/msaf1980/go-matcher/pkg/items"
+)
+
+const (
+	formatTSV  = "tsv"
+	formatJSON = "json"
+)
+
+var (
+	errPatternsNotSet = errors.New("patterns file not set")
+)
+
+type errFormatInvalid struct {
+	Format string
+}
+
+func (e errFormatInvalid) Error() string {
+	return "invalid output format: " + e.Format
+}
+
+type errPatternInvalid struct {
+	Line    int
+	Pattern string
+	Err     error
+}
+
+func (e errPatternInvalid) Error() string {
+	return "invalid pattern at line " + strconv.Itoa(e.Line) + ": '" + e.Pattern + "' " + e.Err.Error()
+}
+
+type config struct {
+	patterns string
+	format   string
+	globs    bool
+	first    bool
+	inverse  bool
+	counts   bool
+	dialect  gtags.Dialect
+}
+
+// matcher match plain (dot-separated) and tagged paths against patterns, loaded from single file
+type matcher struct {
+	globs   *gglob.GGlobTree
+	queries *gtags.GTagsTree
+
+	patterns []string // normalized patterns by index (line number)
+	counts   []int    // matched paths count by index
+
+	store items.AllStore
+}
+
+func newMatcher(dialect gtags.Dialect) *matcher {
+	return &matcher{
+		globs:   gglob.NewTree(),
+		queries: gtags.NewTreeDialect(dialect),
+	}
+}
+
+// load read patterns (one per line, empty lines are skipped), pattern index is a line number (from 0)
+func (m *matcher) load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+	index := -1
+	for scanner.Scan() {
+		index++
+		pattern := strings.TrimSpace(scanner.Text())
+		m.patterns = append(m.patterns, "")
+		if pattern == "" {
+			continue
+		}
+		var (
+			normalized string
+			err        error
+		)
+		if strings.HasPrefix(pattern, "seriesByTag(") {
+			normalized, _, err = m.queries.Add(pattern, index)
+		} else {
+			normalized, _, err = m.globs.Add(pattern, index)
+		}
+		if err == glob.ErrGlobExist {
+			// duplicate, matched by first pattern index
+			continue
+		} else if err != nil {
+			return errPatternInvalid{Line: index + 1, Pattern: pattern, Err: err}
+		}
+		m.patterns[index] = normalized
+	}
+	m.counts = make([]int, len(m.patterns))
+
+	return scanner.Err()
+}
+
+// match path (plain or tagged, in graphite (name;a=v1), GraphiteMergeTree (name?a=v1) or Prometheus (name{a="v1"}) format)
+func (m *matcher) match(path string) (matched []int, err error) {
+	m.store.Init()
+	if pos := strings.IndexAny(path, ";?{"); pos == -1 {
+		_ = m.globs.Match(path, &m.store)
+	} else {
+		var tags []gtags.Tag
+		switch path[pos] {
+		case ';':
+			tags, err = gtags.GraphitePathTags(path)
+		case '?':
+			tags, err = gtags.PathTags(path)
+		default:
+			tags, err = gtags.PrometheusTags(path)
+		}
+		if err != nil {
+			return
+		}
+		gtags.SortTags(tags)
+		_ = m.queries.MatchByTags(tags, &m.store)
+	}
+
+	matched = m.store.Index.N
+	sort.Ints(matched)
+
+	return
+}
+
+func (m *matcher) run(cfg config, in io.Reader, out, errOut io.Writer) error {
+	var (
+		paths, matchedPaths int
+		result              pathResult
+	)
+
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+	for scanner.Scan() {
+		path := strings.TrimSpace(scanner.Text())
+		if path == "" {
+			continue
+		}
+		paths++
+		matched, err := m.match(path)
+		if err != nil {
+			fmt.Fprintf(errOut, "%s: %v\n", path, err)
+		}
+		if cfg.first && len(matched) > 1 {
+			matched = matched[:1]
+		}
+		if len(matched) > 0 {
+			matchedPaths++
+			for _, n := range matched {
+				m.counts[n]++
+			}
+		}
+
+		if cfg.counts || (cfg.inverse && len(matched) > 0) {
+			continue
+		}
+
+		if cfg.format == formatJSON {
+			result.reset(path)
+			if !cfg.inverse {
+				if cfg.globs {
+					for _, n := range matched {
+						result.Patterns = append(result.Patterns, m.patterns[n])
+					}
+				} else {
+					result.Index = append(result.Index, matched...)
+				}
+			}
+			if err = enc.Encode(&result); err != nil {
+				return err
+			}
+		} else {
+			w.WriteString(path)
+			for _, n := range matched {
+				w.WriteByte('\t')
+				if cfg.globs {
+					w.WriteString(m.patterns[n])
+				} else {
+					w.WriteString(strconv.Itoa(n))
+				}
+			}
+			w.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if cfg.counts {
+		if err := m.writeCounts(cfg, w, errOut, paths, matchedPaths); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+func (m *matcher) writeCounts(cfg config, w *bufio.Writer, errOut io.Writer, paths, matchedPaths int) error {
+	summary := countsSummary{
+		Paths:     paths,
+		Matched:   matchedPaths,
+		Unmatched: paths - matchedPaths,
+		Patterns:  make([]patternCount, 0),
+	}
+	for n, count := range m.counts {
+		if count > 0 {
+			summary.Patterns = append(summary.Patterns, patternCount{Index: n, Pattern: m.patterns[n], Count: count})
+		}
+	}
+
+	if cfg.format == formatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(&summary)
+	}
+
+	for _, p := range summary.Patterns {
+		w.WriteString(strconv.Itoa(p.Count))
+		w.WriteByte('\t')
+		w.WriteString(strconv.Itoa(p.Index))
+		w.WriteByte('\t')
+		w.WriteString(p.Pattern)
+		w.WriteByte('\n')
+	}
+	fmt.Fprintf(errOut, "paths: %d, matched: %d, unmatched: %d\n", summary.Paths, summary.Matched, summary.Unmatched)
+
+	return nil
+}
+
+type pathResult struct {
+	Path     string   `json:"path"`
+	Index    []int    `json:"index,omitempty"`
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+func (r *pathResult) reset(path string) {
+	r.Path = path
+	r.Index = r.Index[:0]
+	r.Patterns = r.Patterns[:0]
+}
+
+type patternCount struct {
+	Index   int    `json:"index"`
+	Pattern string `json:"pattern"`
+	Count   int    `json:"count"`
+}
+
+type countsSummary struct {
+	Paths     int            `json:"paths"`
+	Matched   int            `json:"matched"`
+	Unmatched int            `json:"unmatched"`
+	Patterns  []patternCount `json:"patterns"`
+}
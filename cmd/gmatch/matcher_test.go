@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/msaf1980/go-matcher/gtags"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPatterns = `a.b.c
+a.*.c
+
+seriesByTag('name=cpu', 'host=h*')
+a.*.c
+seriesByTag('name=cpu')
+`
+
+func Test_run(t *testing.T) {
+	patterns := filepath.Join(t.TempDir(), "patterns.txt")
+	if err := os.WriteFile(patterns, []byte(testPatterns), 0644); err != nil {
+		t.Fatal(err)
+	}
+	in := "a.b.c\na.d.c\nb.c\ncpu;host=h1;dc=x\ncpu?host=n1\n"
+
+	tests := []struct {
+		name    string
+		cfg     config
+		want    string
+		wantErr string
+	}{
+		{
+			name: "tsv",
+			cfg:  config{format: formatTSV},
+			want: "a.b.c\t0\t1\na.d.c\t1\nb.c\ncpu;host=h1;dc=x\t3\t5\ncpu?host=n1\t5\n",
+		},
+		{
+			name: "tsv globs first",
+			cfg:  config{format: formatTSV, globs: true, first: true},
+			want: "a.b.c\ta.b.c\na.d.c\ta.*.c\nb.c\ncpu;host=h1;dc=x\tseriesByTag('__name__=cpu','host=h*')\ncpu?host=n1\tseriesByTag('__name__=cpu')\n",
+		},
+		{
+			name: "tsv inverse",
+			cfg:  config{format: formatTSV, inverse: true},
+			want: "b.c\n",
+		},
+		{
+			name:    "tsv counts",
+			cfg:     config{format: formatTSV, counts: true},
+			want:    "1\t0\ta.b.c\n2\t1\ta.*.c\n1\t3\tseriesByTag('__name__=cpu','host=h*')\n2\t5\tseriesByTag('__name__=cpu')\n",
+			wantErr: "paths: 5, matched: 4, unmatched: 1\n",
+		},
+		{
+			name: "json",
+			cfg:  config{format: formatJSON},
+			want: `{"path":"a.b.c","index":[0,1]}` + "\n" + `{"path":"a.d.c","index":[1]}` + "\n" + `{"path":"b.c"}` + "\n" +
+				`{"path":"cpu;host=h1;dc=x","index":[3,5]}` + "\n" + `{"path":"cpu?host=n1","index":[5]}` + "\n",
+		},
+		{
+			name: "json globs inverse",
+			cfg:  config{format: formatJSON, globs: true, inverse: true},
+			want: `{"path":"b.c"}` + "\n",
+		},
+		{
+			name: "json counts first",
+			cfg:  config{format: formatJSON, counts: true, first: true},
+			want: `{"paths":5,"matched":4,"unmatched":1,"patterns":[{"index":0,"pattern":"a.b.c","count":1},{"index":1,"pattern":"a.*.c","count":1},` +
+				`{"index":3,"pattern":"seriesByTag('__name__=cpu','host=h*')","count":1},{"index":5,"pattern":"seriesByTag('__name__=cpu')","count":1}]}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			tt.cfg.patterns = patterns
+			if err := run(tt.cfg, strings.NewReader(in), &out, &errOut); err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			assert.Equal(t, tt.want, out.String())
+			assert.Equal(t, tt.wantErr, errOut.String())
+		})
+	}
+}
+
+func Test_run_Error(t *testing.T) {
+	patterns := filepath.Join(t.TempDir(), "patterns.txt")
+	if err := os.WriteFile(patterns, []byte("a.b\na.{b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out, errOut bytes.Buffer
+
+	err := run(config{format: formatTSV}, strings.NewReader(""), &out, &errOut)
+	assert.Equal(t, errPatternsNotSet, err)
+
+	err = run(config{patterns: patterns, format: "xml"}, strings.NewReader(""), &out, &errOut)
+	assert.Equal(t, errFormatInvalid{"xml"}, err)
+
+	err = run(config{patterns: patterns, format: formatTSV}, strings.NewReader(""), &out, &errOut)
+	if _, ok := err.(errPatternInvalid); !ok {
+		t.Errorf("run() error = %v, want errPatternInvalid", err)
+	}
+}
+
+func Test_matcher_match(t *testing.T) {
+	m := newMatcher(gtags.DialectGraphiteClickHouse)
+	if err := m.load(strings.NewReader(testPatterns)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want []int
+	}{
+		{path: "a.b.c", want: []int{0, 1}},
+		{path: "cpu;host=h1;dc=x", want: []int{3, 5}},
+		{path: "cpu?host=n1", want: []int{5}},
+		{path: `cpu{dc="x",host="h1"} 1`, want: []int{3, 5}},
+		{path: `mem{host="h1"} 1`, want: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := m.match(tt.path)
+			if err != nil {
+				t.Fatalf("match(%q) error = %v", tt.path, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -35,3 +35,23 @@ func BenchmarkExpand(b *testing.B) {
 	}
 	b.Log("\n")
 }
+
+func BenchmarkIterator(b *testing.B) {
+	var input = []string{
+		"1[b-e]2[a-c]3",
+		"232{ad,fdff,wwwww,asdasd}[z-A]",
+		"metric.{us,ru,en,de,dk,gb,in}server[1-4].cpu.[0-3].{idle,sys,user}",
+	}
+	for _, in := range input {
+		b.Run(in, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				it := NewIterator(in, 0)
+				for {
+					if _, ok := it.Next(); !ok {
+						break
+					}
+				}
+			}
+		})
+	}
+}
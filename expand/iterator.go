@@ -0,0 +1,114 @@
+package expand
+
+import (
+	"io"
+	"math"
+	"strings"
+)
+
+// Iterator is a lazy expansion iterator (yields expanded strings one at a time, in the same order as Expand)
+type Iterator struct {
+	exps    []Expression
+	n       int   // expanded expressions count, exps[n:] are written as is (restricted by depth)
+	offsets []int // buf length before expression write
+	buf     []byte
+
+	started bool
+	done    bool
+}
+
+// NewIterator takes the string contains the shell expansion expression and returns lazy expansion iterator.
+//
+// Argument depth for restict expanded nodes (dot-separated), > 0 - restuct expanded nodes, 0 - unlimited.
+func NewIterator(in string, depth int) *Iterator {
+	exps := ParseExpr(in)
+
+	it := &Iterator{
+		exps: exps.exps,
+		n:    len(exps.exps),
+		buf:  exps.buf[:0],
+	}
+
+	if depth > 0 {
+		depth++
+		for i := 0; i < len(it.exps); i++ {
+			switch it.exps[i].typ {
+			case expString, expWildcard:
+				depth -= strings.Count(it.exps[i].body, ".")
+				if depth <= 1 {
+					it.n = i
+				}
+			}
+			if it.n != len(it.exps) {
+				break
+			}
+		}
+	}
+	it.offsets = make([]int, it.n)
+
+	return it
+}
+
+// Count returns expanded results count (overflow is set if count is greater than max int)
+func (it *Iterator) Count() (n int, overflow bool) {
+	n = 1
+	for i := 0; i < it.n; i++ {
+		c := it.exps[i].count()
+		if c == 0 {
+			return 0, false
+		}
+		if n > math.MaxInt/c {
+			overflow = true
+			n = math.MaxInt
+		} else if !overflow {
+			n *= c
+		}
+	}
+	return
+}
+
+// Next returns next expanded string (false if iterator is exhausted)
+func (it *Iterator) Next() (string, bool) {
+	if it.done {
+		return "", false
+	}
+
+	var (
+		err error
+		i   int
+	)
+	if it.started {
+		// find last not exhausted expression
+		for i = it.n - 1; i >= 0; i-- {
+			it.buf = it.buf[:it.offsets[i]]
+			if it.buf, err = it.exps[i].appendNext(it.buf); err == nil {
+				break
+			}
+			it.exps[i].reset()
+		}
+		if i < 0 {
+			it.done = true
+			return "", false
+		}
+		i++
+	} else {
+		it.started = true
+	}
+
+	for ; i < it.n; i++ {
+		it.offsets[i] = len(it.buf)
+		if it.buf, err = it.exps[i].appendNext(it.buf); err == io.EOF {
+			// empty expression
+			it.done = true
+			return "", false
+		}
+	}
+	s := it.buf
+	for j := it.n; j < len(it.exps); j++ {
+		s = append(s, it.exps[j].body...)
+	}
+	// keep expanded part, tail is rewritten on next call
+	it.buf = s[:len(it.buf)]
+
+	return string(s), true
+}
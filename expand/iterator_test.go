@@ -0,0 +1,74 @@
+package expand
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIterator(t *testing.T) {
+	tests := []struct {
+		in    string
+		depth int
+	}{
+		{in: "abc"},
+		{in: "{b,c}"},
+		{in: "a{,b,c}d"},
+		{in: "a{b,c,}d"},
+		{in: "[2-4]"},
+		{in: "1[b-e]2[a-c]3"},
+		{in: "as{12,32}[a-c]{2}"},
+		{in: "as{12,32}.[a-c].{2}", depth: 1},
+		{in: "as{12,32}{2}.[a-c]", depth: 1},
+		{in: "as{12,32}.2[a-c]", depth: 1},
+		{in: "as{12,32}[a-c]{2}", depth: 2},
+		{in: "as{12,32}.{2}[a-c]", depth: 2},
+		{in: "as{12,32}.[a-c].{2,a}", depth: 2},
+		{in: "as{12,32}.[a-c].{2,a}", depth: 3},
+		{in: "a{b,c}*d"},
+		{in: "a*{b,c}d"},
+		{in: "a{*b,c}d"},
+		{in: "metric.{us,ru,en,de,dk,gb,in}server[1-4].cpu.[0-3].{idle,sys,user}"},
+	}
+	for n, tt := range tests {
+		t.Run(fmt.Sprintf("[%d] [%d] %s", n, tt.depth, tt.in), func(t *testing.T) {
+			want, err := Expand(tt.in, -1, tt.depth)
+			require.NoError(t, err)
+
+			it := NewIterator(tt.in, tt.depth)
+			count, overflow := it.Count()
+			assert.False(t, overflow)
+			assert.Equal(t, len(want), count, "Count()")
+
+			got := make([]string, 0, len(want))
+			for {
+				s, ok := it.Next()
+				if !ok {
+					break
+				}
+				got = append(got, s)
+			}
+			assert.Equal(t, want, got)
+
+			_, ok := it.Next()
+			assert.False(t, ok, "Next() after exhausted")
+		})
+	}
+}
+
+func TestIterator_Overflow(t *testing.T) {
+	it := NewIterator("{a,b}[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]", 0)
+	n, overflow := it.Count()
+	assert.True(t, overflow)
+	assert.Equal(t, math.MaxInt, n)
+
+	s, ok := it.Next()
+	assert.True(t, ok)
+	assert.Equal(t, "a00000000000000000000", s)
+	s, ok = it.Next()
+	assert.True(t, ok)
+	assert.Equal(t, "a00000000000000000001", s)
+}
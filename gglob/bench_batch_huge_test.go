@@ -222,3 +222,52 @@ func BenchmarkBatchHuge_List_Tree_Prealloc_ByParts(b *testing.B) {
 	d := time.Since(start) // TODO: Golang 1.20 has b.Elapsed() method
 	b.ReportMetric(float64(b.N*len(pathsBatchHugeMoira))/d.Seconds(), "match/s")
 }
+
+func BenchmarkBatchHuge_List_Tree_MatchBatch(b *testing.B) {
+	g := parseGGlobs(globsBatchHugeMoira)
+	w := NewTree()
+	for j := 0; j < len(g); j++ {
+		_, _, err := w.AddGlob(g[j], j)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	pathsBatchHugeMoira := generatePaths(gGlobsBatchHugeMoira, len(globsBatchHugeMoira))
+
+	start := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = w.MatchBatch(pathsBatchHugeMoira, 0)
+	}
+	b.StopTimer()
+	d := time.Since(start) // TODO: Golang 1.20 has b.Elapsed() method
+	b.ReportMetric(float64(b.N*len(pathsBatchHugeMoira))/d.Seconds(), "match/s")
+}
+
+func BenchmarkBatchHuge_List_Tree_MatchBatchFunc(b *testing.B) {
+	g := parseGGlobs(globsBatchHugeMoira)
+	w := NewTree()
+	for j := 0; j < len(g); j++ {
+		_, _, err := w.AddGlob(g[j], j)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	pathsBatchHugeMoira := generatePaths(gGlobsBatchHugeMoira, len(globsBatchHugeMoira))
+	first := make([]int, len(pathsBatchHugeMoira))
+
+	start := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.MatchBatchFunc(pathsBatchHugeMoira, 0, func(n int, index []int) {
+			if len(index) == 0 {
+				first[n] = -1
+			} else {
+				first[n] = index[0]
+			}
+		})
+	}
+	b.StopTimer()
+	d := time.Since(start) // TODO: Golang 1.20 has b.Elapsed() method
+	b.ReportMetric(float64(b.N*len(pathsBatchHugeMoira))/d.Seconds(), "match/s")
+}
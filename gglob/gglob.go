@@ -30,7 +30,8 @@ func (g *GGlob) Match(path string) (matched bool) {
 	if len(path) < g.MinLen {
 		return
 	}
-	if g.MaxLen > 0 && len(path) > g.MaxLen {
+	// MaxLen is a parts len sum (without dots)
+	if g.MaxLen > 0 && len(path)-partsCount+1 > g.MaxLen {
 		return
 	}
 
@@ -91,7 +92,7 @@ func Parse(s string) (gg *GGlob, err error) {
 	for nextParts != "" {
 		part, nextParts, _ = strings.Cut(nextParts, ".")
 		if part == "" {
-			err = items.ErrNodeEmpty{s}
+			err = items.ErrNodeEmpty{Path: s}
 			return
 		}
 
@@ -124,3 +125,9 @@ func ParseMust(s string) *GGlob {
 		return gg
 	}
 }
+
+// ToRegexp convert dot-separated glob to equivalent regexp (without anchors, must be matched against the whole string),
+// * and ? don't match dot
+func ToRegexp(s string) (string, error) {
+	return glob.ToRegexpAny(s, "[^.]")
+}
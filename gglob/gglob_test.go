@@ -220,3 +220,51 @@ func _BenchmarkRegex_ASCII_Precompiled(b *testing.B) {
 		}
 	}
 }
+
+func TestGGlob_Match_MaxLen(t *testing.T) {
+	// MaxLen is a parts len sum, dots are not counted
+	for glob, paths := range map[string][]string{
+		"a.b.c":      {"a.b.c"},
+		"a?.b?":      {"ax.by", "aя.bя"},
+		"a.{b,cd}.e": {"a.b.e", "a.cd.e"},
+	} {
+		g := ParseMust(glob)
+		for _, path := range paths {
+			assert.True(t, g.Match(path), "%q must match %q", glob, path)
+		}
+	}
+	assert.False(t, ParseMust("a?.b?").Match("axx.b"))
+}
+
+func TestToRegexp(t *testing.T) {
+	tests := []struct {
+		glob  string
+		want  string
+		match []string
+		miss  []string
+	}{
+		{glob: "a.b", want: `a\.b`, match: []string{"a.b"}, miss: []string{"acb"}},
+		{glob: "a.*", want: `a\.[^.]*`, match: []string{"a.b", "a.bc"}, miss: []string{"a.b.c", "ab.c"}},
+		{glob: "a?.b", want: `a[^.]\.b`, match: []string{"ac.b"}, miss: []string{"a.b", "a..b"}},
+		{glob: "a.{b,c}*.d", want: `a\.(?:b|c)[^.]*\.d`, match: []string{"a.b.d", "a.cz.d"}, miss: []string{"a.bz.z.d", "a.d.d"}},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.glob, func(t *testing.T) {
+			got, err := ToRegexp(tt.glob)
+			if err != nil {
+				t.Fatalf("ToRegexp(%q) error = %v", tt.glob, err)
+			}
+			assert.Equal(t, tt.want, got)
+			re := regexp.MustCompile("^(?:" + got + ")$")
+			g := ParseMust(tt.glob)
+			for _, s := range tt.match {
+				assert.True(t, re.MatchString(s), "regexp %q must match %q", got, s)
+				assert.True(t, g.Match(s), "glob %q must match %q", tt.glob, s)
+			}
+			for _, s := range tt.miss {
+				assert.False(t, re.MatchString(s), "regexp %q must not match %q", got, s)
+				assert.False(t, g.Match(s), "glob %q must not match %q", tt.glob, s)
+			}
+		})
+	}
+}
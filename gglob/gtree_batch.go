@@ -0,0 +1,36 @@
+package gglob
+
+import (
+	"github.com/msaf1980/go-matcher/pkg/items"
+)
+
+// MatchBatch match paths in parallel with workers goroutines (workers < 1 - use GOMAXPROCS)
+// and return matched globs indexes for each path (nil if path not matched).
+//
+// Tree must not be modified during match.
+func (gtree *GGlobTree) MatchBatch(paths []string, workers int) (matched [][]int) {
+	matched = make([][]int, len(paths))
+	gtree.MatchBatchFunc(paths, workers, func(n int, index []int) {
+		if len(index) > 0 {
+			matched[n] = append([]int(nil), index...)
+		}
+	})
+	return
+}
+
+// MatchBatchFunc match paths in parallel with workers goroutines (workers < 1 - use GOMAXPROCS)
+// and stream matched globs indexes for each path through f (n is a path position in paths).
+//
+// f called concurrently from workers and index slice reused after f return, so copy it if needed.
+// Tree must not be modified during match.
+func (gtree *GGlobTree) MatchBatchFunc(paths []string, workers int, f func(n int, index []int)) {
+	items.Batch(len(paths), workers, func(start, end int) {
+		var store items.IndexStore
+		store.Grow(4)
+		for i := start; i < end; i++ {
+			store.Init()
+			_ = gtree.Match(paths[i], &store)
+			f(i, store.N)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package gglob
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/msaf1980/go-matcher/pkg/items"
+)
+
+func TestGGlobTree_MatchBatch(t *testing.T) {
+	w := NewTree()
+	for j := 0; j < len(globsBatchHugeMoira); j++ {
+		if _, _, err := w.Add(globsBatchHugeMoira[j], j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := generatePaths(gGlobsBatchHugeMoira, len(globsBatchHugeMoira))
+	paths = append(paths, "", "not.exist.path")
+
+	want := make([][]int, len(paths))
+	for i, path := range paths {
+		var store items.IndexStore
+		if w.Match(path, &store) > 0 {
+			want[i] = store.N
+		}
+	}
+
+	for _, workers := range []int{0, 1, 3} {
+		if got := w.MatchBatch(paths, workers); !reflect.DeepEqual(want, got) {
+			t.Errorf("GGlobTree.MatchBatch(%d) mismatch", workers)
+		}
+	}
+}
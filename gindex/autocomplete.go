@@ -0,0 +1,132 @@
+package gindex
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/msaf1980/go-matcher/gtags"
+)
+
+// graphite tag name for __name__
+const nameTag = "name"
+
+func tagKey(tag string) string {
+	if tag == nameTag {
+		return "__name__"
+	}
+	return tag
+}
+
+func tagName(key string) string {
+	if key == "__name__" {
+		return nameTag
+	}
+	return key
+}
+
+// filter evaluate seriesByTag conditions (graphite tagspecs, like name=a) and return matched series ids,
+// all is set when no conditions (all series are matched)
+func (idx *TagsIndex) filter(exprs []string) (terms gtags.TaggedTermList, ids []int, all bool, err error) {
+	if len(exprs) == 0 {
+		all = true
+		return
+	}
+	if terms, err = gtags.ParseTaggedConditions(exprs); err != nil {
+		return
+	}
+	ids = idx.Find(terms)
+	return
+}
+
+// AutoCompleteTags return sorted tags names (graphite /tags/autoComplete/tags semantics), exist in series, matched with exprs.
+// Tags from exprs are excluded. __name__ is returned as name. limit <= 0 - unlimited.
+func (idx *TagsIndex) AutoCompleteTags(exprs []string, prefix string, limit int) (tags []string, err error) {
+	var (
+		terms gtags.TaggedTermList
+		ids   []int
+		all   bool
+	)
+	if terms, ids, all, err = idx.filter(exprs); err != nil {
+		return
+	}
+	if !all && len(ids) == 0 {
+		return
+	}
+
+	candidates := make([]string, 0, len(idx.Keys))
+LOOP:
+	for _, key := range idx.Keys {
+		for i := range terms {
+			if terms[i].Key == key {
+				// searched tag
+				continue LOOP
+			}
+		}
+		name := tagName(key)
+		if strings.HasPrefix(name, prefix) {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+
+	for _, name := range candidates {
+		if all || hasIntersect(ids, idx.Tags[tagKey(name)].allPostings()) {
+			tags = append(tags, name)
+			if limit > 0 && len(tags) == limit {
+				break
+			}
+		}
+	}
+	return
+}
+
+// AutoCompleteValues return sorted tag values (graphite /tags/autoComplete/values semantics), exist in series, matched with exprs.
+// name tag is an alias for __name__. limit <= 0 - unlimited.
+func (idx *TagsIndex) AutoCompleteValues(exprs []string, tag, prefix string, limit int) (values []string, err error) {
+	var (
+		ids []int
+		all bool
+	)
+	if _, ids, all, err = idx.filter(exprs); err != nil {
+		return
+	}
+	if !all && len(ids) == 0 {
+		return
+	}
+
+	tagValues, ok := idx.Tags[tagKey(tag)]
+	if !ok {
+		return
+	}
+	keys := tagValues.Values
+	if prefix != "" {
+		keys = keys[sort.SearchStrings(keys, prefix):]
+	}
+	for _, v := range keys {
+		if !strings.HasPrefix(v, prefix) {
+			break
+		}
+		if all || hasIntersect(ids, tagValues.Postings[v]) {
+			values = append(values, v)
+			if limit > 0 && len(values) == limit {
+				break
+			}
+		}
+	}
+	return
+}
+
+// hasIntersect check for common ids in sorted ids lists
+func hasIntersect(a, b []int) bool {
+	var i, j int
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			return true
+		} else if a[i] < b[j] {
+			i++
+		} else {
+			j++
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package gindex
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTagsIndex_AutoCompleteTags(t *testing.T) {
+	idx := newTestTagsIndex(t)
+
+	tests := []struct {
+		exprs   []string
+		prefix  string
+		limit   int
+		want    []string
+		wantErr bool
+	}{
+		{want: []string{"dc", "env", "host", "name"}},
+		{prefix: "n", want: []string{"name"}},
+		{limit: 2, want: []string{"dc", "env"}},
+		{exprs: []string{"name=cpu"}, want: []string{"dc", "host"}},
+		{exprs: []string{"name=mem", "dc=b"}, want: []string{"env", "host"}},
+		{exprs: []string{"dc=c"}, want: []string{"host", "name"}},
+		{exprs: []string{"dc=c"}, prefix: "na", want: []string{"name"}},
+		{exprs: []string{"host=h4"}, want: []string{"name"}},
+		{exprs: []string{"host=h0"}},
+		{exprs: []string{"host"}, wantErr: true},
+	}
+	for n, tt := range tests {
+		t.Run(fmt.Sprintf("%d#%v#%s", n, tt.exprs, tt.prefix), func(t *testing.T) {
+			got, err := idx.AutoCompleteTags(tt.exprs, tt.prefix, tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TagsIndex.AutoCompleteTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf("TagsIndex.AutoCompleteTags() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestTagsIndex_AutoCompleteValues(t *testing.T) {
+	idx := newTestTagsIndex(t)
+
+	tests := []struct {
+		exprs   []string
+		tag     string
+		prefix  string
+		limit   int
+		want    []string
+		wantErr bool
+	}{
+		{tag: "name", want: []string{"cpu", "disk.used", "mem"}},
+		{tag: "__name__", prefix: "d", want: []string{"disk.used"}},
+		{tag: "host", limit: 2, want: []string{"h1", "h2"}},
+		{tag: "none"},
+		{exprs: []string{"name=mem"}, tag: "host", want: []string{"h1", "h3"}},
+		{exprs: []string{"name=cpu"}, tag: "dc", want: []string{"a", "b"}},
+		{exprs: []string{"dc=a"}, tag: "name", want: []string{"cpu", "mem"}},
+		{exprs: []string{"dc=a"}, tag: "name", prefix: "m", want: []string{"mem"}},
+		{exprs: []string{"name=~^(cpu|disk)", "dc!=a"}, tag: "host", want: []string{"h3", "h4", "h5"}},
+		{exprs: []string{"name=cpu"}, tag: "env"},
+		{exprs: []string{"name=~("}, tag: "env", wantErr: true},
+	}
+	for n, tt := range tests {
+		t.Run(fmt.Sprintf("%d#%v#%s#%s", n, tt.exprs, tt.tag, tt.prefix), func(t *testing.T) {
+			got, err := idx.AutoCompleteValues(tt.exprs, tt.tag, tt.prefix, tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TagsIndex.AutoCompleteValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf("TagsIndex.AutoCompleteValues() = %s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package gindex
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/msaf1980/go-matcher/gglob"
+	"github.com/msaf1980/go-matcher/glob"
+	"github.com/msaf1980/go-matcher/pkg/items"
+)
+
+// Node is a metric name node (one path level)
+type Node struct {
+	Leaf bool // metric with path to node exist
+
+	Childs map[string]*Node // next level nodes
+	Keys   []string         // sorted Childs keys (for prefix pruning)
+}
+
+func (node *Node) child(key string) *Node {
+	if node.Childs == nil {
+		node.Childs = make(map[string]*Node)
+	}
+	child, ok := node.Childs[key]
+	if !ok {
+		child = &Node{}
+		node.Childs[key] = child
+		pos := sort.SearchStrings(node.Keys, key)
+		node.Keys = append(node.Keys, "")
+		copy(node.Keys[pos+1:], node.Keys[pos:])
+		node.Keys[pos] = key
+	}
+	return child
+}
+
+// FindResult is a graphite /metrics/find result node
+type FindResult struct {
+	Path   string // full path to node (like a.b.c)
+	Leaf   bool   // metric exist
+	Branch bool   // node has childs
+}
+
+// GIndex is in-memory metric names index (level-based trie), writted for graphite project
+type GIndex struct {
+	Root *Node
+	N    int // metrics count
+}
+
+func New() *GIndex {
+	return &GIndex{Root: &Node{}}
+}
+
+// Add add metric path (dot-separated, like a.b.c) to index
+func (idx *GIndex) Add(path string) (err error) {
+	var parts []string
+	if parts, err = splitPath(path); err != nil {
+		return
+	}
+	idx.add(parts)
+	return
+}
+
+// splitPath split metric path into parts and check for empty parts (before index modification)
+func splitPath(path string) ([]string, error) {
+	path, _ = gglob.PathLevel(path)
+	if path == "" {
+		return nil, items.ErrNodeEmpty{Path: path}
+	}
+	parts := strings.Split(path, ".")
+	for _, part := range parts {
+		if part == "" {
+			return nil, items.ErrNodeEmpty{Path: path}
+		}
+	}
+	return parts, nil
+}
+
+func (idx *GIndex) add(parts []string) {
+	node := idx.Root
+	for _, part := range parts {
+		node = node.child(part)
+	}
+	if !node.Leaf {
+		node.Leaf = true
+		idx.N++
+	}
+}
+
+// Load bulk load metric paths (one per line, empty lines are skipped) into index.
+// Paths are validated before load, so index is not changed on error
+func (idx *GIndex) Load(r io.Reader) (err error) {
+	var paths [][]string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+	for scanner.Scan() {
+		path := strings.TrimSpace(scanner.Text())
+		if path == "" {
+			continue
+		}
+		var parts []string
+		if parts, err = splitPath(path); err != nil {
+			return
+		}
+		paths = append(paths, parts)
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	for _, parts := range paths {
+		idx.add(parts)
+	}
+	return
+}
+
+// LoadFile bulk load metric paths (one per line, empty lines are skipped) from file into index
+func (idx *GIndex) LoadFile(filename string) (err error) {
+	var f *os.File
+	if f, err = os.Open(filename); err != nil {
+		return
+	}
+	defer f.Close()
+	return idx.Load(f)
+}
+
+// Find return nodes matched with glob (graphite /metrics/find semantics, one result per node at the glob's depth)
+func (idx *GIndex) Find(globString string) (result []FindResult, err error) {
+	var gg *gglob.GGlob
+	if gg, err = gglob.Parse(globString); err != nil {
+		return
+	}
+	if len(gg.Parts) == 0 {
+		return
+	}
+	buf := make([]byte, 0, len(globString)+32)
+	result = find(idx.Root, gg.Parts, buf, result)
+	return
+}
+
+// FindGlob is like Find, but with parsed glob
+func (idx *GIndex) FindGlob(gg *gglob.GGlob) (result []FindResult) {
+	if len(gg.Parts) == 0 {
+		return
+	}
+	buf := make([]byte, 0, len(gg.Node)+32)
+	return find(idx.Root, gg.Parts, buf, result)
+}
+
+func find(node *Node, parts []*glob.Glob, buf []byte, result []FindResult) []FindResult {
+	if len(node.Childs) == 0 {
+		return result
+	}
+	if len(buf) > 0 {
+		buf = append(buf, '.')
+	}
+	g := parts[0]
+	if len(g.Items) == 0 {
+		// string
+		if child, ok := node.Childs[g.Node]; ok {
+			result = findNext(child, g.Node, parts[1:], buf, result)
+		}
+		return result
+	}
+
+	keys := node.Keys
+	if g.Prefix != "" {
+		// prefix pruning
+		start := sort.SearchStrings(keys, g.Prefix)
+		keys = keys[start:]
+	}
+	for _, key := range keys {
+		if g.Prefix != "" && !strings.HasPrefix(key, g.Prefix) {
+			break
+		}
+		if g.Match(key) {
+			result = findNext(node.Childs[key], key, parts[1:], buf, result)
+		}
+	}
+
+	return result
+}
+
+func findNext(node *Node, key string, parts []*glob.Glob, buf []byte, result []FindResult) []FindResult {
+	buf = append(buf, key...)
+	if len(parts) == 0 {
+		return append(result, FindResult{Path: string(buf), Leaf: node.Leaf, Branch: len(node.Childs) > 0})
+	}
+	return find(node, parts, buf, result)
+}
@@ -0,0 +1,136 @@
+package gindex
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/msaf1980/go-matcher/pkg/items"
+	"github.com/stretchr/testify/assert"
+)
+
+var testMetrics = `
+a.b.c
+a.b.c.d
+a.b.e
+a.bc.c
+a.cd.c
+ab.b.c
+b.b.c
+
+b
+`
+
+func TestGIndex_Load(t *testing.T) {
+	idx := New()
+	if err := idx.Load(strings.NewReader(testMetrics)); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 8, idx.N)
+	assert.Equal(t, []string{"a", "ab", "b"}, idx.Root.Keys)
+	assert.Equal(t, []string{"b", "bc", "cd"}, idx.Root.Childs["a"].Keys)
+
+	// duplicate
+	if err := idx.Add("a.b.c."); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 8, idx.N)
+
+	assert.Equal(t, items.ErrNodeEmpty{Path: "a..b"}, idx.Add("a..b"))
+	assert.Equal(t, items.ErrNodeEmpty{Path: ""}, idx.Add(""))
+}
+
+func TestGIndex_Add_Failed(t *testing.T) {
+	idx := New()
+	if err := idx.Load(strings.NewReader(testMetrics)); err != nil {
+		t.Fatal(err)
+	}
+	want := make(map[string][]FindResult)
+	for _, glob := range []string{"*", "a.*", "a.b.*", "p.*"} {
+		result, err := idx.Find(glob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[glob] = result
+	}
+
+	assert.Equal(t, items.ErrNodeEmpty{Path: "x..y"}, idx.Add("x..y"))
+	assert.Equal(t, items.ErrNodeEmpty{Path: "p.q..r"}, idx.Add("p.q..r"))
+	assert.Equal(t, items.ErrNodeEmpty{Path: "a.b.x..y"}, idx.Add("a.b.x..y"))
+	// load is not partially applied
+	assert.Equal(t, items.ErrNodeEmpty{Path: "p..r"}, idx.Load(strings.NewReader("p.q.r\nz\np..r\n")))
+	assert.Equal(t, 8, idx.N)
+
+	for glob, result := range want {
+		got, err := idx.Find(glob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, result, got, glob)
+	}
+}
+
+func TestGIndex_Find(t *testing.T) {
+	idx := New()
+	if err := idx.Load(strings.NewReader(testMetrics)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		glob    string
+		want    []FindResult
+		wantErr bool
+	}{
+		{
+			glob: "*",
+			want: []FindResult{{Path: "a", Branch: true}, {Path: "ab", Branch: true}, {Path: "b", Leaf: true, Branch: true}},
+		},
+		{
+			glob: "a.*",
+			want: []FindResult{{Path: "a.b", Branch: true}, {Path: "a.bc", Branch: true}, {Path: "a.cd", Branch: true}},
+		},
+		{
+			glob: "a.b*",
+			want: []FindResult{{Path: "a.b", Branch: true}, {Path: "a.bc", Branch: true}},
+		},
+		{
+			glob: "a.b.*",
+			want: []FindResult{{Path: "a.b.c", Leaf: true, Branch: true}, {Path: "a.b.e", Leaf: true}},
+		},
+		{
+			glob: "a.b.c",
+			want: []FindResult{{Path: "a.b.c", Leaf: true, Branch: true}},
+		},
+		{
+			glob: "{a,ab}.b.c",
+			want: []FindResult{{Path: "a.b.c", Leaf: true, Branch: true}, {Path: "ab.b.c", Leaf: true}},
+		},
+		{
+			glob: "*.*.c",
+			want: []FindResult{
+				{Path: "a.b.c", Leaf: true, Branch: true}, {Path: "a.bc.c", Leaf: true}, {Path: "a.cd.c", Leaf: true},
+				{Path: "ab.b.c", Leaf: true}, {Path: "b.b.c", Leaf: true},
+			},
+		},
+		{
+			glob: "a.?.*.d",
+			want: []FindResult{{Path: "a.b.c.d", Leaf: true}},
+		},
+		{glob: "a.b.c.d.e"},
+		{glob: "c.*"},
+		{glob: "a.[bc]d.c", want: []FindResult{{Path: "a.cd.c", Leaf: true}}},
+		{glob: "a..c", wantErr: true},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.glob, func(t *testing.T) {
+			got, err := idx.Find(tt.glob)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GIndex.Find(%q) error = %v, wantErr %v", tt.glob, err, tt.wantErr)
+			}
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf("GIndex.Find(%q) = %s", tt.glob, cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package gindex
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/msaf1980/go-matcher/gtags"
+)
+
+// TagValues is a tag values dictionary with postings (series ids lists)
+type TagValues struct {
+	Values   []string         // sorted values
+	Postings map[string][]int // sorted series ids by value
+}
+
+func (t *TagValues) add(value string, id int) {
+	ids, ok := t.Postings[value]
+	if !ok {
+		pos := sort.SearchStrings(t.Values, value)
+		t.Values = append(t.Values, "")
+		copy(t.Values[pos+1:], t.Values[pos:])
+		t.Values[pos] = value
+	}
+	// ids are added in ascending order
+	if len(ids) == 0 || ids[len(ids)-1] != id {
+		t.Postings[value] = append(ids, id)
+	}
+}
+
+// allPostings return sorted series ids, contains tag (tag with empty value is absent, like in graphite)
+func (t *TagValues) allPostings() []int {
+	values := t.Values
+	if len(values) > 0 && values[0] == "" {
+		values = values[1:]
+	}
+	if len(values) == 1 {
+		return t.Postings[values[0]]
+	}
+	var ids []int
+	for _, v := range values {
+		ids = append(ids, t.Postings[v]...)
+	}
+	return uniqueInts(ids)
+}
+
+// TagsIndex is in-memory tagged series inverted index (tag=value postings), for evaluate seriesByTag against stored series
+type TagsIndex struct {
+	Series []string       // series names (like name;a=v1;b=v2) by id
+	Names  map[string]int // series ids by name
+
+	Tags map[string]*TagValues // tag values dictionaries by tag key
+	Keys []string              // sorted tags keys
+}
+
+func NewTagsIndex() *TagsIndex {
+	return &TagsIndex{
+		Names: make(map[string]int),
+		Tags:  make(map[string]*TagValues),
+	}
+}
+
+// Add add graphite tagged series (like name;a=v1;b=v2) to index and return series id
+func (idx *TagsIndex) Add(path string) (id int, err error) {
+	var (
+		tags []gtags.Tag
+		ok   bool
+	)
+	if id, ok = idx.Names[path]; ok {
+		return
+	}
+	if tags, err = gtags.GraphitePathTags(path); err != nil {
+		return -1, err
+	}
+	return idx.AddTags(path, tags), nil
+}
+
+// AddTags add series with parsed tags to index and return series id
+func (idx *TagsIndex) AddTags(name string, tags []gtags.Tag) (id int) {
+	var ok bool
+	if id, ok = idx.Names[name]; ok {
+		return
+	}
+	id = len(idx.Series)
+	idx.Series = append(idx.Series, name)
+	idx.Names[name] = id
+	for _, tag := range tags {
+		values, ok := idx.Tags[tag.Key]
+		if !ok {
+			values = &TagValues{Postings: make(map[string][]int)}
+			idx.Tags[tag.Key] = values
+			pos := sort.SearchStrings(idx.Keys, tag.Key)
+			idx.Keys = append(idx.Keys, "")
+			copy(idx.Keys[pos+1:], idx.Keys[pos:])
+			idx.Keys[pos] = tag.Key
+		}
+		values.add(tag.Value, id)
+	}
+	return
+}
+
+// termPostings return union of postings for values, matched (or not matched if not) with term
+func (idx *TagsIndex) termPostings(term *gtags.TaggedTerm, matched bool) []int {
+	if term.IsWildcardKey() {
+		// union for all matched keys
+		var ids []int
+		n := 0
+		for _, key := range idx.Keys {
+			if !term.MatchKey(key) {
+				continue
+			}
+			if keyIds := valuesPostings(idx.Tags[key], term, matched, true); len(keyIds) > 0 {
+				ids = append(ids, keyIds...)
+				n++
+			}
+		}
+		if n > 1 {
+			ids = uniqueInts(ids)
+		}
+		return ids
+	}
+	values, ok := idx.Tags[term.Key]
+	if !ok {
+		return nil
+	}
+	return valuesPostings(values, term, matched, false)
+}
+
+// absentPostings return sorted ids of series without tag
+func (idx *TagsIndex) absentPostings(key string) []int {
+	ids := idx.allIds()
+	if values, ok := idx.Tags[key]; ok {
+		ids = subtractInts(ids, values.allPostings())
+	}
+	return ids
+}
+
+// presentPostings return sorted ids of series with tag
+func (idx *TagsIndex) presentPostings(key string) []int {
+	if values, ok := idx.Tags[key]; ok {
+		return values.allPostings()
+	}
+	return nil
+}
+
+func (idx *TagsIndex) allIds() []int {
+	ids := make([]int, len(idx.Series))
+	for i := range ids {
+		ids[i] = i
+	}
+	return ids
+}
+
+// valuesPostings return union of postings for tag values, matched (or not matched if not) with term.
+// Empty values are skipped for wildcard key (tag with empty value is absent)
+func valuesPostings(values *TagValues, term *gtags.TaggedTerm, matched, wildcardKey bool) []int {
+	if (term.Op == gtags.TaggedTermEq || term.Op == gtags.TaggedTermNe) && !term.HasWildcard {
+		// literal
+		if wildcardKey && term.Value == "" {
+			return nil
+		}
+		return values.Postings[term.Value]
+	}
+
+	keys := values.Values
+	var prefix string
+	if term.HasWildcard && term.Op == gtags.TaggedTermEq && term.Glob != nil {
+		// prefix pruning
+		prefix = term.Glob.Prefix
+		keys = keys[sort.SearchStrings(keys, prefix):]
+	}
+	var ids []int
+	n := 0
+	for _, v := range keys {
+		if prefix != "" && !strings.HasPrefix(v, prefix) {
+			break
+		}
+		if v == "" && wildcardKey {
+			continue
+		}
+		if term.Match(v) == matched {
+			ids = append(ids, values.Postings[v]...)
+			n++
+		}
+	}
+	if n > 1 {
+		ids = uniqueInts(ids)
+	}
+	return ids
+}
+
+// Find evaluate seriesByTag terms and return matched series ids (sorted).
+// Absent tag is an empty value (graphite semantics), so terms, matched empty value, also match series without tag
+func (idx *TagsIndex) Find(terms gtags.TaggedTermList) (ids []int) {
+	if len(terms) == 0 {
+		return
+	}
+	positive := false
+	for i := range terms {
+		switch terms[i].Op {
+		case gtags.TaggedTermEq, gtags.TaggedTermMatch, gtags.TaggedTermGt, gtags.TaggedTermGe, gtags.TaggedTermLt, gtags.TaggedTermLe:
+			termIds := idx.termPostings(&terms[i], true)
+			if !terms[i].IsWildcardKey() && terms[i].MatchEmpty() {
+				// postings can be shared with index, so copy
+				termIds = uniqueInts(append(append([]int(nil), termIds...), idx.absentPostings(terms[i].Key)...))
+			}
+			ids = intersectInts(ids, termIds, positive)
+			positive = true
+			if len(ids) == 0 {
+				return nil
+			}
+		}
+	}
+	if !positive {
+		// only negative terms, start from all series
+		ids = idx.allIds()
+	}
+	for i := range terms {
+		switch terms[i].Op {
+		case gtags.TaggedTermNe, gtags.TaggedTermNotMatch:
+			if !terms[i].IsWildcardKey() && !terms[i].MatchEmpty() {
+				// absent tag is not matched, so exclude series without tag
+				ids = intersectInts(ids, idx.presentPostings(terms[i].Key), true)
+			}
+			// exclude series with values, not matched with term
+			ids = subtractInts(ids, idx.termPostings(&terms[i], false))
+			if len(ids) == 0 {
+				return nil
+			}
+		}
+	}
+	return
+}
+
+// FindNames evaluate seriesByTag terms and return matched series names (sorted by id)
+func (idx *TagsIndex) FindNames(terms gtags.TaggedTermList) (names []string) {
+	ids := idx.Find(terms)
+	if len(ids) == 0 {
+		return
+	}
+	names = make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = idx.Series[id]
+	}
+	return
+}
+
+// FindQuery parse seriesByTag query, evaluate and return matched series ids (sorted)
+func (idx *TagsIndex) FindQuery(query string) (ids []int, err error) {
+	var terms gtags.TaggedTermList
+	if terms, err = gtags.ParseSeriesByTag(query); err != nil {
+		return
+	}
+	return idx.Find(terms), nil
+}
+
+// uniqueInts sort and remove duplicates
+func uniqueInts(a []int) []int {
+	if len(a) < 2 {
+		return a
+	}
+	sort.Ints(a)
+	j := 0
+	for i := 1; i < len(a); i++ {
+		if a[i] != a[j] {
+			j++
+			a[j] = a[i]
+		}
+	}
+	return a[:j+1]
+}
+
+// intersectInts intersect sorted ids lists (if !init, b is copied)
+func intersectInts(a, b []int, init bool) []int {
+	if !init {
+		return append([]int(nil), b...)
+	}
+	var i, j, n int
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			a[n] = a[i]
+			n++
+			i++
+			j++
+		} else if a[i] < b[j] {
+			i++
+		} else {
+			j++
+		}
+	}
+	return a[:n]
+}
+
+// subtractInts remove sorted b ids from sorted a
+func subtractInts(a, b []int) []int {
+	if len(b) == 0 {
+		return a
+	}
+	var i, j, n int
+	for i < len(a) {
+		for j < len(b) && b[j] < a[i] {
+			j++
+		}
+		if j == len(b) || b[j] != a[i] {
+			a[n] = a[i]
+			n++
+		}
+		i++
+	}
+	return a[:n]
+}
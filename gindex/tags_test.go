@@ -0,0 +1,156 @@
+package gindex
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/msaf1980/go-matcher/gtags"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSeries = []string{
+	"cpu;dc=a;host=h1",
+	"cpu;dc=a;host=h2",
+	"cpu;dc=b;host=h3",
+	"cpu;host=h4",
+	"mem;dc=a;host=h1",
+	"mem;dc=b;env=test;host=h3",
+	"disk.used;dc=c;host=h5",
+}
+
+func newTestTagsIndex(t *testing.T) *TagsIndex {
+	idx := NewTagsIndex()
+	for i, path := range testSeries {
+		id, err := idx.Add(path)
+		if err != nil {
+			t.Fatalf("TagsIndex.Add(%q) error = %v", path, err)
+		}
+		assert.Equal(t, i, id)
+	}
+	return idx
+}
+
+func TestTagsIndex_Add(t *testing.T) {
+	idx := newTestTagsIndex(t)
+	id, err := idx.Add("cpu;host=h4")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, id)
+
+	assert.Equal(t, []string{"__name__", "dc", "env", "host"}, idx.Keys)
+	assert.Equal(t, []string{"a", "b", "c"}, idx.Tags["dc"].Values)
+	assert.Equal(t, []int{0, 1, 4}, idx.Tags["dc"].Postings["a"])
+	assert.Equal(t, []int{0, 4}, idx.Tags["host"].Postings["h1"])
+}
+
+func TestTagsIndex_Find(t *testing.T) {
+	idx := newTestTagsIndex(t)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "seriesByTag('name=cpu')", want: []string{"cpu;dc=a;host=h1", "cpu;dc=a;host=h2", "cpu;dc=b;host=h3", "cpu;host=h4"}},
+		{query: "seriesByTag('name=cpu', 'dc=a')", want: []string{"cpu;dc=a;host=h1", "cpu;dc=a;host=h2"}},
+		{query: "seriesByTag('name=cpu', 'dc=z')"},
+		{query: "seriesByTag('name=cpu', 'none=z')"},
+		{query: "seriesByTag('dc=a', 'host=h1')", want: []string{"cpu;dc=a;host=h1", "mem;dc=a;host=h1"}},
+		{query: "seriesByTag('name=cpu', 'dc!=a')", want: []string{"cpu;dc=b;host=h3", "cpu;host=h4"}},
+		{query: "seriesByTag('name=*', 'host=h{1,3}')", want: []string{"cpu;dc=a;host=h1", "cpu;dc=b;host=h3", "mem;dc=a;host=h1", "mem;dc=b;env=test;host=h3"}},
+		{query: "seriesByTag('name=disk.*')", want: []string{"disk.used;dc=c;host=h5"}},
+		{query: "seriesByTag('name=~^(cpu|mem)$', 'dc=~b')", want: []string{"cpu;dc=b;host=h3", "mem;dc=b;env=test;host=h3"}},
+		{query: "seriesByTag('name=mem', 'env!=~te')", want: []string{"mem;dc=a;host=h1"}},
+		{query: "seriesByTag('name=mem', 'env!=*')", want: []string{"mem;dc=a;host=h1"}},
+		{query: "seriesByTag('dc!=a', 'host!=h3')", want: []string{"cpu;host=h4", "disk.used;dc=c;host=h5"}},
+		{query: "seriesByTag('host=h*', 'dc!=~^[ab]$', 'name!=cpu')", want: []string{"disk.used;dc=c;host=h5"}},
+		{query: "seriesByTag('name=cpu', 'host>=0')"}, // not numeric values
+		{query: "seriesByTag('name=mem', '{dc,env}=test')", want: []string{"mem;dc=b;env=test;host=h3"}},
+		{query: "seriesByTag('name=mem', '~^(dc|env)$!=test')", want: []string{"mem;dc=a;host=h1"}},
+		{query: "seriesByTag('name=~^(cpu|mem)$', '~^e!=~.*')", want: []string{"cpu;dc=a;host=h1", "cpu;dc=a;host=h2", "cpu;dc=b;host=h3", "cpu;host=h4", "mem;dc=a;host=h1"}},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.query, func(t *testing.T) {
+			terms, err := gtags.ParseSeriesByTag(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := idx.FindNames(terms)
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf("TagsIndex.FindNames(%q) = %s", tt.query, cmp.Diff(tt.want, got))
+			}
+
+			// verify with brute-force match
+			var want []string
+			for _, path := range testSeries {
+				tags, _ := gtags.GraphitePathTags(path)
+				if terms.MatchByTags(tags) {
+					want = append(want, path)
+				}
+			}
+			if !cmp.Equal(want, got) {
+				t.Errorf("TagsIndex.FindNames(%q) != TaggedTermList.MatchByTags %s", tt.query, cmp.Diff(want, got))
+			}
+
+			ids, err := idx.FindQuery(tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, len(got), len(ids))
+		})
+	}
+}
+
+func TestTagsIndex_Find_Empty(t *testing.T) {
+	series := []string{"m;a=1;b=x", "m;b=y", "m;a=2", "m;c=1"}
+	idx := NewTagsIndex()
+	for _, path := range series {
+		if _, err := idx.Add(path); err != nil {
+			t.Fatalf("TagsIndex.Add(%q) error = %v", path, err)
+		}
+	}
+	// absent tag is an empty value (graphite semantics)
+	for _, query := range []string{
+		"seriesByTag('name=m', 'a=')",
+		"seriesByTag('name=m', 'a!=')",
+		"seriesByTag('name=m', 'a=~.*')",
+		"seriesByTag('name=m', 'a!=~.*')",
+		"seriesByTag('name=m', 'a=~^$')",
+		"seriesByTag('name=m', 'a!=~^$')",
+		"seriesByTag('a=')",
+		"seriesByTag('a!=')",
+		"seriesByTag('a!=1')",
+		"seriesByTag('a!=*')",
+		"seriesByTag('a=~^1?$')",
+		"seriesByTag('a!=~1', 'b!=')",
+		"seriesByTag('a=', 'b=')",
+		"seriesByTag('name=m', 'd=')",
+		"seriesByTag('name=m', 'd!=')",
+		"seriesByTag('name=m', '~^[ab]$=')",
+		"seriesByTag('name=m', '~^[ab]$!=')",
+		"seriesByTag('name=m', '~^[ab]$=~.*')",
+		"seriesByTag('name=m', '~^[ab]$!=~^$')",
+	} {
+		t.Run(query, func(t *testing.T) {
+			terms, err := gtags.ParseSeriesByTag(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []string
+			for _, path := range series {
+				tags, _ := gtags.GraphitePathTags(path)
+				if terms.MatchByTags(tags) {
+					want = append(want, path)
+				}
+			}
+			got := idx.FindNames(terms)
+			if !cmp.Equal(want, got) {
+				t.Errorf("TagsIndex.FindNames(%q) != TaggedTermList.MatchByTags %s", query, cmp.Diff(want, got))
+			}
+		})
+	}
+
+	values, err := idx.AutoCompleteValues([]string{"a="}, "b", "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"y"}, values)
+	tags, err := idx.AutoCompleteTags([]string{"a!="}, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "name"}, tags)
+}
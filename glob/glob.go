@@ -95,7 +95,7 @@ func Parse(glob string) (g *Glob, err error) {
 		}
 		end := items.IndexLastWildcard(glob)
 		if end == 0 && glob[0] != '?' && glob[0] != '*' {
-			err = items.ErrNodeUnclosed{glob}
+			err = items.ErrNodeUnclosed{Segment: glob}
 			return
 		}
 		if end < len(glob)-1 {
@@ -0,0 +1,76 @@
+package glob
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/msaf1980/go-matcher/pkg/items"
+)
+
+// ToRegexp convert glob to equivalent regexp (without anchors, must be matched against the whole string)
+func ToRegexp(glob string) (string, error) {
+	return ToRegexpAny(glob, ".")
+}
+
+// ToRegexpAny convert glob to equivalent regexp, any is a regexp for any symbol in * and ? (like [^.] for dot-separated globs)
+func ToRegexpAny(glob, any string) (string, error) {
+	var buf strings.Builder
+	buf.Grow(len(glob) + 8)
+	for glob != "" {
+		pos := items.IndexWildcard(glob)
+		if pos == -1 {
+			buf.WriteString(regexp.QuoteMeta(glob))
+			break
+		}
+		if pos > 0 {
+			buf.WriteString(regexp.QuoteMeta(glob[:pos]))
+			glob = glob[pos:]
+		}
+		switch glob[0] {
+		case '*':
+			buf.WriteString(any)
+			buf.WriteByte('*')
+			glob = glob[1:]
+		case '?':
+			buf.WriteString(any)
+			glob = glob[1:]
+		case '[':
+			end := strings.IndexByte(glob, ']')
+			if end == -1 {
+				return "", items.ErrNodeUnclosed{Segment: glob}
+			}
+			if end == 1 {
+				// empty range
+				glob = glob[2:]
+				continue
+			}
+			buf.WriteByte('[')
+			for i := 1; i < end; i++ {
+				c := glob[i]
+				if c == '\\' || c == '[' || (c == '^' && i == 1) {
+					buf.WriteByte('\\')
+				}
+				buf.WriteByte(c)
+			}
+			buf.WriteByte(']')
+			glob = glob[end+1:]
+		case '{':
+			end := strings.IndexByte(glob, '}')
+			if end == -1 {
+				return "", items.ErrNodeUnclosed{Segment: glob}
+			}
+			buf.WriteString("(?:")
+			for i, s := range strings.Split(glob[1:end], ",") {
+				if i > 0 {
+					buf.WriteByte('|')
+				}
+				buf.WriteString(regexp.QuoteMeta(s))
+			}
+			buf.WriteByte(')')
+			glob = glob[end+1:]
+		default:
+			return "", items.ErrNodeUnclosed{Segment: glob}
+		}
+	}
+	return buf.String(), nil
+}
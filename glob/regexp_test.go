@@ -0,0 +1,52 @@
+package glob
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToRegexp(t *testing.T) {
+	tests := []struct {
+		glob    string
+		want    string
+		wantErr bool
+		match   []string
+		miss    []string
+	}{
+		{glob: "a.b", want: `a\.b`, match: []string{"a.b"}, miss: []string{"acb"}},
+		{glob: "a*b?", want: `a.*b.`, match: []string{"ab1", "a.cb1"}, miss: []string{"ab", "ab12"}},
+		{glob: "a[0-9^]", want: `a[0-9^]`, match: []string{"a1", "a^"}, miss: []string{"ab"}},
+		{glob: "a[^0]", want: `a[\^0]`, match: []string{"a0", "a^"}, miss: []string{"a1"}},
+		{glob: "a[]b", want: `ab`, match: []string{"ab"}},
+		{glob: "{a.b,c}d", want: `(?:a\.b|c)d`, match: []string{"a.bd", "cd"}, miss: []string{"a.bcd", "acbd"}},
+		{glob: "a{b,}", want: `a(?:b|)`, match: []string{"a", "ab"}},
+		{glob: "a[b", wantErr: true},
+		{glob: "a{b", wantErr: true},
+		{glob: "a}b", wantErr: true},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.glob, func(t *testing.T) {
+			got, err := ToRegexp(tt.glob)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToRegexp(%q) error = %v, wantErr %v", tt.glob, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tt.want, got)
+			re := regexp.MustCompile("^(?:" + got + ")$")
+			g := ParseMust(tt.glob)
+			for _, s := range tt.match {
+				assert.True(t, re.MatchString(s), "regexp %q must match %q", got, s)
+				assert.True(t, g.Match(s), "glob %q must match %q", tt.glob, s)
+			}
+			for _, s := range tt.miss {
+				assert.False(t, re.MatchString(s), "regexp %q must not match %q", got, s)
+				assert.False(t, g.Match(s), "glob %q must not match %q", tt.glob, s)
+			}
+		})
+	}
+}
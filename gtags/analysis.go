@@ -0,0 +1,511 @@
+package gtags
+
+import (
+	"math"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/msaf1980/go-matcher/gglob"
+	"github.com/msaf1980/go-matcher/glob"
+	"github.com/msaf1980/go-matcher/pkg/items"
+)
+
+// Relation is a seriesByTag queries analysis result
+type Relation int8
+
+const (
+	RelationNo      Relation = iota // queries are not related
+	RelationYes                     // queries are related
+	RelationUnknown                 // can't be analysed (complex regexps, numeric or wildcard key terms)
+)
+
+var (
+	stringsRelation = []string{"no", "yes", "unknown"}
+)
+
+func (r Relation) String() string {
+	return stringsRelation[r]
+}
+
+// and combine results for independent conditions (no is first, then unknown)
+func (r Relation) and(other Relation) Relation {
+	if r == RelationNo || other == RelationNo {
+		return RelationNo
+	}
+	if r == RelationUnknown || other == RelationUnknown {
+		return RelationUnknown
+	}
+	return RelationYes
+}
+
+func relation(b bool) Relation {
+	if b {
+		return RelationYes
+	}
+	return RelationNo
+}
+
+// freshValue is a value, not matched by literals (for check negative terms)
+const freshValue = "\x00"
+
+// termCond is a term (or term negation) condition on tag value
+type termCond struct {
+	term *TaggedTerm
+	not  bool
+}
+
+func (c termCond) match(v string) bool {
+	return c.term.Match(v) != c.not
+}
+
+// literal return value for condition, matched only one value (tag=value)
+func (c termCond) literal() (string, bool) {
+	if c.term.HasWildcard {
+		return "", false
+	}
+	if (c.term.Op == TaggedTermEq && !c.not) || (c.term.Op == TaggedTermNe && c.not) {
+		return c.term.Value, true
+	}
+	return "", false
+}
+
+// pattern check for glob or regexp condition, positive is true for values, matched by pattern (without negation)
+func (c termCond) pattern() (positive, ok bool) {
+	switch c.term.Op {
+	case TaggedTermEq, TaggedTermMatch:
+		return !c.not, c.term.HasWildcard || c.term.Re != nil
+	case TaggedTermNe, TaggedTermNotMatch:
+		return c.not, c.term.HasWildcard || c.term.Re != nil
+	}
+	return false, false
+}
+
+// samples return candidate values for condition
+func (c termCond) samples() []string {
+	term := c.term
+	if term.Op.IsNumeric() {
+		return []string{
+			term.Value,
+			strconv.FormatFloat(term.Number+1, 'g', -1, 64),
+			strconv.FormatFloat(term.Number-1, 'g', -1, 64),
+		}
+	}
+	if !term.HasWildcard && term.Re == nil {
+		return []string{term.Value}
+	}
+	expr := term.Value
+	if term.GGlob != nil {
+		s, err := gglob.ToRegexp(term.Value)
+		if err != nil {
+			return nil
+		}
+		expr = "^(?:" + s + ")$"
+	} else if term.Glob != nil {
+		s, err := glob.ToRegexp(term.Value)
+		if err != nil {
+			return nil
+		}
+		expr = "^(?:" + s + ")$"
+	}
+	return regexpSamples(expr)
+}
+
+// regexpSamples return shortest and longer candidate strings for regexp (must be verified, assertions like \b are ignored)
+func regexpSamples(expr string) (samples []string) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	re = re.Simplify()
+	for _, long := range []bool{false, true} {
+		var buf strings.Builder
+		if regexpSample(re, &buf, long) {
+			samples = append(samples, buf.String())
+		}
+	}
+	return
+}
+
+func regexpSample(re *syntax.Regexp, buf *strings.Builder, long bool) bool {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return false
+	case syntax.OpLiteral:
+		buf.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return false
+		}
+		if long {
+			buf.WriteRune(re.Rune[len(re.Rune)-1])
+		} else {
+			buf.WriteRune(re.Rune[0])
+		}
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		buf.WriteByte('x')
+	case syntax.OpCapture, syntax.OpPlus:
+		return regexpSample(re.Sub[0], buf, long)
+	case syntax.OpStar, syntax.OpQuest:
+		if long {
+			return regexpSample(re.Sub[0], buf, long)
+		}
+	case syntax.OpRepeat:
+		n := re.Min
+		if long && n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			if !regexpSample(re.Sub[0], buf, long) {
+				return false
+			}
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !regexpSample(sub, buf, long) {
+				return false
+			}
+		}
+	case syntax.OpAlternate:
+		subs := re.Sub
+		if long {
+			subs = subs[len(subs)-1:]
+		}
+		for _, sub := range subs {
+			var alt strings.Builder
+			if regexpSample(sub, &alt, long) {
+				buf.WriteString(alt.String())
+				return true
+			}
+		}
+		return false
+	}
+	// empty match and assertions
+	return true
+}
+
+func matchAll(conds []termCond, v string) bool {
+	for _, c := range conds {
+		if !c.match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// disjoint check for patterns, which never match the same value (by prefilters)
+func disjoint(a, b *Prefilter) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if !strings.HasPrefix(a.Prefix, b.Prefix) && !strings.HasPrefix(b.Prefix, a.Prefix) {
+		return true
+	}
+	// suffix is checked without trailing dot for dot-separated glob
+	if a.TrimDot == b.TrimDot && !strings.HasSuffix(a.Suffix, b.Suffix) && !strings.HasSuffix(b.Suffix, a.Suffix) {
+		return true
+	}
+	return (a.MaxLen != -1 && a.MaxLen < b.MinLen) || (b.MaxLen != -1 && b.MaxLen < a.MinLen)
+}
+
+// excluded check for all values of positive pattern condition are excluded by negative pattern condition
+func excluded(neg, pos termCond) bool {
+	if pos.match("") && neg.match("") {
+		return false
+	}
+	n, p := neg.term, pos.term
+	if n.Value == p.Value && (n.Re == nil) == (p.Re == nil) && (n.GGlob == nil) == (p.GGlob == nil) {
+		// the same pattern
+		return true
+	}
+	// prefix*suffix glob
+	if g := n.Glob; g != nil && len(g.Items) == 1 && p.Prefilter != nil && !p.Prefilter.TrimDot {
+		if _, ok := g.Items[0].(items.Star); ok {
+			pf := p.Prefilter
+			return strings.HasPrefix(pf.Prefix, g.Prefix) && strings.HasSuffix(pf.Suffix, g.Suffix) &&
+				pf.MinLen >= len(g.Prefix)+len(g.Suffix)
+		}
+	}
+	return false
+}
+
+// numericCheck check numeric conditions bounds, return witness value (if found) or conflict
+func numericCheck(conds []termCond) (witness string, conflict bool) {
+	var (
+		lo, hi             = math.Inf(-1), math.Inf(1)
+		loStrict, hiStrict bool
+		found              bool
+	)
+	for _, c := range conds {
+		if c.not || !c.term.Op.IsNumeric() {
+			continue
+		}
+		found = true
+		n := c.term.Number
+		switch c.term.Op {
+		case TaggedTermGt, TaggedTermGe:
+			if n > lo || (n == lo && c.term.Op == TaggedTermGt) {
+				lo, loStrict = n, c.term.Op == TaggedTermGt
+			}
+		default:
+			if n < hi || (n == hi && c.term.Op == TaggedTermLt) {
+				hi, hiStrict = n, c.term.Op == TaggedTermLt
+			}
+		}
+	}
+	if !found {
+		return "", false
+	}
+	if lo > hi || (lo == hi && (loStrict || hiStrict)) {
+		return "", true
+	}
+	for _, c := range conds {
+		if !c.not || !c.term.Op.IsNumeric() {
+			continue
+		}
+		// all values in bounds are excluded by negated condition
+		n := c.term.Number
+		switch c.term.Op {
+		case TaggedTermGt:
+			conflict = lo > n || (lo == n && loStrict)
+		case TaggedTermGe:
+			conflict = lo >= n
+		case TaggedTermLt:
+			conflict = hi < n || (hi == n && hiStrict)
+		default:
+			conflict = hi <= n
+		}
+		if conflict {
+			return "", true
+		}
+	}
+	var v float64
+	switch {
+	case math.IsInf(lo, -1) && math.IsInf(hi, 1):
+		v = 0
+	case math.IsInf(lo, -1):
+		v = hi - 1
+	case math.IsInf(hi, 1):
+		v = lo + 1
+	default:
+		v = lo + (hi-lo)/2
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64), false
+}
+
+// satisfiable check for value, matched by all conditions (on the same key)
+func satisfiable(conds []termCond) Relation {
+	for _, c := range conds {
+		if v, ok := c.literal(); ok {
+			// exact
+			return relation(matchAll(conds, v))
+		}
+	}
+	if matchAll(conds, "") || matchAll(conds, freshValue) {
+		return RelationYes
+	}
+	witness, conflict := numericCheck(conds)
+	if conflict {
+		return RelationNo
+	}
+	if witness != "" && matchAll(conds, witness) {
+		return RelationYes
+	}
+	var samples []string
+	for _, c := range conds {
+		samples = append(samples, c.samples()...)
+	}
+	for _, v := range samples {
+		if matchAll(conds, v) {
+			return RelationYes
+		}
+	}
+	// concatenated samples for unanchored regexps
+	for _, v1 := range samples {
+		for _, v2 := range samples {
+			if matchAll(conds, v1+v2) {
+				return RelationYes
+			}
+		}
+	}
+	// try to prove, that conditions are never matched together
+	for i := range conds {
+		if positive, ok := conds[i].pattern(); !ok || !positive {
+			continue
+		}
+		for j := range conds {
+			if i == j {
+				continue
+			}
+			positive, ok := conds[j].pattern()
+			if !ok {
+				continue
+			}
+			if positive {
+				if disjoint(conds[i].term.Prefilter, conds[j].term.Prefilter) {
+					return RelationNo
+				}
+			} else if excluded(conds[j], conds[i]) {
+				return RelationNo
+			}
+		}
+	}
+	return RelationUnknown
+}
+
+// keyConds group terms conditions by key (wildcard is true, if terms with wildcard keys are skipped)
+func keyConds(conds map[string][]termCond, terms []*TaggedTerm) (wildcard bool) {
+	for _, term := range terms {
+		if term.IsWildcardKey() {
+			wildcard = true
+			continue
+		}
+		conds[term.Key] = append(conds[term.Key], termCond{term: term})
+	}
+	return
+}
+
+func satisfiableAll(conds map[string][]termCond) Relation {
+	result := RelationYes
+	for _, c := range conds {
+		if result = result.and(satisfiable(c)); result == RelationNo {
+			break
+		}
+	}
+	return result
+}
+
+func termsPtr(terms TaggedTermList) []*TaggedTerm {
+	pterms := make([]*TaggedTerm, len(terms))
+	for i := range terms {
+		pterms[i] = &terms[i]
+	}
+	return pterms
+}
+
+// Overlaps check for queries, which can match the same series
+func Overlaps(a, b TaggedTermList) Relation {
+	return overlaps(termsPtr(a), termsPtr(b))
+}
+
+func overlaps(a, b []*TaggedTerm) Relation {
+	conds := make(map[string][]termCond)
+	wildcard := keyConds(conds, a)
+	if keyConds(conds, b) {
+		wildcard = true
+	}
+	result := satisfiableAll(conds)
+	if wildcard && result == RelationYes {
+		// wildcard keys terms are not analysed
+		return RelationUnknown
+	}
+	return result
+}
+
+// Covers check for a query match all series, matched by b query
+func Covers(a, b TaggedTermList) Relation {
+	return covers(termsPtr(a), termsPtr(b))
+}
+
+func covers(a, b []*TaggedTerm) Relation {
+	conds := make(map[string][]termCond)
+	wildcard := keyConds(conds, b)
+	self := satisfiableAll(conds)
+	if self == RelationNo {
+		// b never matched
+		return RelationYes
+	}
+	// not covered term is an unknown, if b is not exactly matched
+	notCovered := RelationNo
+	if wildcard || self == RelationUnknown {
+		notCovered = RelationUnknown
+	}
+	result := RelationYes
+	for _, term := range a {
+		if term.IsWildcardKey() {
+			result = RelationUnknown
+			continue
+		}
+		keyConds := append(conds[term.Key][:len(conds[term.Key]):len(conds[term.Key])], termCond{term: term, not: true})
+		switch satisfiable(keyConds) {
+		case RelationYes:
+			// b match series with value, not matched by term
+			if result = result.and(notCovered); result == RelationNo {
+				return result
+			}
+		case RelationUnknown:
+			result = RelationUnknown
+		}
+	}
+	return result
+}
+
+// FindOverlaps return sorted indexes of queries, which can match the same series as terms
+// (unknown is for queries, which can't be analysed, like with complex regexps)
+func (gtree *GTagsTree) FindOverlaps(terms TaggedTermList) (overlapped, unknown []int) {
+	pterms := termsPtr(terms)
+	collect := func(r Relation, index int) {
+		switch r {
+		case RelationYes:
+			overlapped = append(overlapped, index)
+		case RelationUnknown:
+			unknown = append(unknown, index)
+		}
+	}
+	if gtree.Terminate {
+		collect(overlaps(pterms, nil), gtree.Terminated.Index)
+	}
+	conds := make(map[string][]termCond)
+	keyConds(conds, pterms)
+	gtree.Root.findOverlaps(pterms, conds, make([]*TaggedTerm, 0, 8), collect)
+	sort.Ints(overlapped)
+	sort.Ints(unknown)
+	return
+}
+
+// FindOverlapsQuery parse query (with tree dialect) and return queries, which can match the same series (like FindOverlaps)
+func (gtree *GTagsTree) FindOverlapsQuery(query string) (overlapped, unknown []int, err error) {
+	var terms TaggedTermList
+	if terms, err = ParseSeriesByTagDialect(query, gtree.Dialect); err != nil {
+		return
+	}
+	overlapped, unknown = gtree.FindOverlaps(terms)
+	return
+}
+
+func (item *TaggedItem) findOverlaps(terms []*TaggedTerm, conds map[string][]termCond, path []*TaggedTerm, collect func(Relation, int)) {
+	visit := func(child *TaggedItem) {
+		if !child.Term.IsWildcardKey() {
+			// prune subtree, if key conditions are never matched together
+			keyConds := append(conds[child.Term.Key][:len(conds[child.Term.Key]):len(conds[child.Term.Key])], termCond{term: child.Term})
+			for _, term := range path {
+				if term.Key == child.Term.Key && !term.IsWildcardKey() {
+					keyConds = append(keyConds, termCond{term: term})
+				}
+			}
+			if satisfiable(keyConds) == RelationNo {
+				return
+			}
+		}
+		p := append(path, child.Term)
+		if child.Terminate {
+			collect(overlaps(terms, p), child.Index)
+		}
+		child.findOverlaps(terms, conds, p, collect)
+	}
+	for i := range item.Items {
+		for _, child := range item.Items[i].MatchedMap {
+			visit(child)
+		}
+		for _, child := range item.Items[i].Matched {
+			visit(child)
+		}
+		for _, child := range item.Items[i].NotMatched {
+			visit(child)
+		}
+	}
+	for _, child := range item.KeyItems {
+		visit(child)
+	}
+}
@@ -0,0 +1,149 @@
+package gtags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlaps(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want Relation
+	}{
+		{a: "seriesByTag('name=a', 'b=c')", b: "seriesByTag('name=a', 'b=d')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b=c')", b: "seriesByTag('name=a', 'd=e')", want: RelationYes},
+		{a: "seriesByTag('name=a')", b: "seriesByTag('name=b')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b=c*')", b: "seriesByTag('name=a', 'b=cd')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b=c*')", b: "seriesByTag('name=a', 'b=d*')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b=*c')", b: "seriesByTag('name=a', 'b=*de')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b=c*')", b: "seriesByTag('name=a', 'b=*d')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b!=c')", b: "seriesByTag('name=a', 'b=c')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b!=c')", b: "seriesByTag('name=a', 'b!=d')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b=')", b: "seriesByTag('name=a', 'b!=')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b!=c*')", b: "seriesByTag('name=a', 'b=c*')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b!=c*')", b: "seriesByTag('name=a', 'b=cd*')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b=~^c')", b: "seriesByTag('name=a', 'b!=~^c')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b=~^c')", b: "seriesByTag('name=a', 'b=~^d')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b=~^c')", b: "seriesByTag('name=a', 'b=~d')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b=~^(c|d)$')", b: "seriesByTag('name=a', 'b=d')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b=~^(c|d)$')", b: "seriesByTag('name=a', 'b=e')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b=~^c[0-9]+$')", b: "seriesByTag('name=a', 'b=c*')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b>5')", b: "seriesByTag('name=a', 'b<3')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b>5')", b: "seriesByTag('name=a', 'b<=5.5')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b>=5')", b: "seriesByTag('name=a', 'b<=5')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b>5')", b: "seriesByTag('name=a', 'b=3')", want: RelationNo},
+		// can't be analysed
+		{a: "seriesByTag('name=a', 'b=~^a.*z$')", b: "seriesByTag('name=a', 'b!=~^a')", want: RelationUnknown},
+		{a: "seriesByTag('name=a', 'b=c?')", b: "seriesByTag('name=a', 'b=*de')", want: RelationUnknown},
+		{a: "seriesByTag('name=a', 'k*=c')", b: "seriesByTag('name=a', 'b=c')", want: RelationUnknown},
+		{a: "seriesByTag('name=a', 'k*=c')", b: "seriesByTag('name=b', 'b=c')", want: RelationNo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"#"+tt.b, func(t *testing.T) {
+			a, err := ParseSeriesByTag(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ParseSeriesByTag(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, Overlaps(a, b), "Overlaps(a, b)")
+			assert.Equal(t, tt.want, Overlaps(b, a), "Overlaps(b, a)")
+		})
+	}
+}
+
+func TestOverlaps_NameGGlob(t *testing.T) {
+	// dot-separated glob match path with trailing dot (a.b.)
+	a, err := ParseSeriesByTagDialect("seriesByTag('name=*.b')", DialectNameGGlob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseSeriesByTagDialect("seriesByTag('name=~b\\.$')", DialectNameGGlob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, a.MatchByTags([]Tag{{Key: "__name__", Value: "a.b."}}))
+	assert.True(t, b.MatchByTags([]Tag{{Key: "__name__", Value: "a.b."}}))
+	assert.NotEqual(t, RelationNo, Overlaps(a, b))
+	assert.NotEqual(t, RelationNo, Overlaps(b, a))
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want Relation
+	}{
+		{a: "seriesByTag('name=a')", b: "seriesByTag('name=a', 'b=c')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b=c')", b: "seriesByTag('name=a')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b=c*')", b: "seriesByTag('name=a', 'b=cd')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b=cd')", b: "seriesByTag('name=a', 'b=c*')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b=c*')", b: "seriesByTag('name=a', 'b=cd*')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b=c*')", b: "seriesByTag('name=a', 'b=c*')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b!=x')", b: "seriesByTag('name=a', 'b=y')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b!=x')", b: "seriesByTag('name=a', 'b=x*')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b=~^(c|d)$')", b: "seriesByTag('name=a', 'b=d')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b>5')", b: "seriesByTag('name=a', 'b>10')", want: RelationYes},
+		{a: "seriesByTag('name=a', 'b>10')", b: "seriesByTag('name=a', 'b>5')", want: RelationNo},
+		{a: "seriesByTag('name=a', 'b>=5', 'b<10')", b: "seriesByTag('name=a', 'b>5', 'b<=6')", want: RelationYes},
+		// b is never matched
+		{a: "seriesByTag('name=x')", b: "seriesByTag('name=a', 'b=c', 'b=d')", want: RelationYes},
+		// can't be analysed
+		{a: "seriesByTag('name=a', 'b=~^c')", b: "seriesByTag('name=a', 'b=~^cd')", want: RelationUnknown},
+		{a: "seriesByTag('name=a', 'k*=c')", b: "seriesByTag('name=a', 'b=c')", want: RelationUnknown},
+		{a: "seriesByTag('name=a', 'b=c')", b: "seriesByTag('name=a', 'k*=c')", want: RelationUnknown},
+		{a: "seriesByTag('name=a', 'b=c')", b: "seriesByTag('name=a', 'b=c', 'k*=c')", want: RelationYes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"#"+tt.b, func(t *testing.T) {
+			a, err := ParseSeriesByTag(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ParseSeriesByTag(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, Covers(a, b))
+		})
+	}
+}
+
+func TestGTagsTree_FindOverlaps(t *testing.T) {
+	gtree := NewTree()
+	queries := []string{
+		"seriesByTag('name=cpu', 'dc=a')",
+		"seriesByTag('name=cpu', 'dc=b')",
+		"seriesByTag('name=cpu', 'dc!=a', 'host=h*')",
+		"seriesByTag('name=mem', 'dc=a')",
+		"seriesByTag('name=~^c', 'dc=~^a.*z$')",
+		"seriesByTag('name=cpu', 'k8s_*=prod')",
+	}
+	for i, query := range queries {
+		if _, _, err := gtree.Add(query, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		query       string
+		wantOverlap []int
+		wantUnknown []int
+	}{
+		{query: "seriesByTag('name=cpu', 'dc=a')", wantOverlap: []int{0}, wantUnknown: []int{5}},
+		{query: "seriesByTag('name=cpu', 'dc=c', 'host=h1')", wantOverlap: []int{2}, wantUnknown: []int{5}},
+		{query: "seriesByTag('name=cpu', 'dc!=~^a')", wantOverlap: []int{1, 2}, wantUnknown: []int{4, 5}},
+		{query: "seriesByTag('name=disk')"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			overlapped, unknown, err := gtree.FindOverlapsQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.wantOverlap, overlapped, "overlapped")
+			assert.Equal(t, tt.wantUnknown, unknown, "unknown")
+		})
+	}
+}
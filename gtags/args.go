@@ -0,0 +1,145 @@
+package gtags
+
+import (
+	"strings"
+)
+
+const seriesByTagFunc = "seriesByTag"
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func skipSpaces(s string, pos int) int {
+	for pos < len(s) && isSpace(s[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// parseStringLiteral parse graphite string literal (quoted with ' or ") at pos and return unescaped value and next position.
+// Backslash escapes only quotes and backslash (\', \", \\), other escape sequences (like \. in regexp) are leaved as is.
+func parseStringLiteral(query string, pos int) (value string, next int, err error) {
+	quote := query[pos]
+	start := pos + 1
+	escaped := false
+	for i := start; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if i+1 < len(query) {
+				switch query[i+1] {
+				case '\\', '\'', '"':
+					escaped = true
+					i++
+				}
+			}
+		case quote:
+			if escaped {
+				value = unescapeStringLiteral(query[start:i])
+			} else {
+				value = query[start:i]
+			}
+			return value, i + 1, nil
+		}
+	}
+	err = ErrQuerySyntax{Query: query, Offset: pos, Reason: "unterminated string"}
+	return
+}
+
+func unescapeStringLiteral(s string) string {
+	var buf strings.Builder
+	buf.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case '\\', '\'', '"':
+				i++
+			}
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String()
+}
+
+// writeEscaped write value for graphite string literal, quoted with ' (quote is not writed)
+func writeEscaped(buf *strings.Builder, value string) {
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\'':
+			buf.WriteString(`\'`)
+		case '\\':
+			if i == len(value)-1 {
+				buf.WriteString(`\\`)
+			} else {
+				switch value[i+1] {
+				case '\\', '\'', '"':
+					buf.WriteString(`\\`)
+				default:
+					buf.WriteByte('\\')
+				}
+			}
+		default:
+			buf.WriteByte(value[i])
+		}
+	}
+}
+
+// SeriesByTagArgs parse seriesByTag call (like seriesByTag('name=a', "b=c")) and return unescaped arguments.
+// Whitespaces are allowed between tokens, trailing comma is allowed (empty arguments are returned as is, like graphite).
+func SeriesByTagArgs(query string) (args []string, err error) {
+	pos := skipSpaces(query, 0)
+	if !strings.HasPrefix(query[pos:], seriesByTagFunc) {
+		err = ErrQuerySyntax{Query: query, Offset: pos, Reason: "seriesByTag not found"}
+		return
+	}
+	pos = skipSpaces(query, pos+len(seriesByTagFunc))
+	if pos == len(query) || query[pos] != '(' {
+		err = ErrQuerySyntax{Query: query, Offset: pos, Reason: "'(' expected"}
+		return
+	}
+	pos = skipSpaces(query, pos+1)
+
+	if pos < len(query) && query[pos] == ')' {
+		// no arguments
+		pos++
+	} else {
+		args = make([]string, 0, strings.Count(query[pos:], ",")+1)
+		for {
+			if pos == len(query) || (query[pos] != '\'' && query[pos] != '"') {
+				err = ErrQuerySyntax{Query: query, Offset: pos, Reason: "string expected"}
+				return
+			}
+			var arg string
+			if arg, pos, err = parseStringLiteral(query, pos); err != nil {
+				return
+			}
+			args = append(args, arg)
+
+			pos = skipSpaces(query, pos)
+			if pos == len(query) {
+				err = ErrQuerySyntax{Query: query, Offset: pos, Reason: "')' expected"}
+				return
+			}
+			if query[pos] == ')' {
+				pos++
+				break
+			}
+			if query[pos] != ',' {
+				err = ErrQuerySyntax{Query: query, Offset: pos, Reason: "',' or ')' expected"}
+				return
+			}
+			pos = skipSpaces(query, pos+1)
+			if pos < len(query) && query[pos] == ')' {
+				// trailing comma
+				pos++
+				break
+			}
+		}
+	}
+
+	if pos = skipSpaces(query, pos); pos != len(query) {
+		err = ErrQuerySyntax{Query: query, Offset: pos, Reason: "unexpected symbols after ')'"}
+	}
+
+	return
+}
@@ -0,0 +1,123 @@
+package gtags
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeriesByTagArgs(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    []string
+		wantErr error
+	}{
+		{query: "seriesByTag()", want: nil},
+		{query: " seriesByTag ( ) ", want: nil},
+		{query: "seriesByTag('')", want: []string{""}},
+		{query: `seriesByTag('a=b',"c=d")`, want: []string{"a=b", "c=d"}},
+		{query: "\tseriesByTag(\n 'a=b' ,\t\"c=d\" , ) \n", want: []string{"a=b", "c=d"}},
+		{query: `seriesByTag('a=it\'s', "b=\"q\"", 'c=\\')`, want: []string{`a=it's`, `b="q"`, `c=\`}},
+		{query: `seriesByTag('a=it"s', "b=it's")`, want: []string{`a=it"s`, `b=it's`}},
+		{query: `seriesByTag('b=~c(a|z)\.a')`, want: []string{`b=~c(a|z)\.a`}},
+		{query: `seriesByTag('a=b',, 'c=d')`, wantErr: ErrQuerySyntax{Query: `seriesByTag('a=b',, 'c=d')`, Offset: 18, Reason: "string expected"}},
+		{query: "", wantErr: ErrQuerySyntax{Query: "", Offset: 0, Reason: "seriesByTag not found"}},
+		{query: "series('a=b')", wantErr: ErrQuerySyntax{Query: "series('a=b')", Offset: 0, Reason: "seriesByTag not found"}},
+		{query: "seriesByTag 'a=b'", wantErr: ErrQuerySyntax{Query: "seriesByTag 'a=b'", Offset: 12, Reason: "'(' expected"}},
+		{query: "seriesByTag(a=b)", wantErr: ErrQuerySyntax{Query: "seriesByTag(a=b)", Offset: 12, Reason: "string expected"}},
+		{query: "seriesByTag('a=b)", wantErr: ErrQuerySyntax{Query: "seriesByTag('a=b)", Offset: 12, Reason: "unterminated string"}},
+		{query: `seriesByTag('a=b\')`, wantErr: ErrQuerySyntax{Query: `seriesByTag('a=b\')`, Offset: 12, Reason: "unterminated string"}},
+		{query: "seriesByTag('a=b' 'c=d')", wantErr: ErrQuerySyntax{Query: "seriesByTag('a=b' 'c=d')", Offset: 18, Reason: "',' or ')' expected"}},
+		{query: "seriesByTag('a=b'", wantErr: ErrQuerySyntax{Query: "seriesByTag('a=b'", Offset: 17, Reason: "')' expected"}},
+		{query: "seriesByTag('a=b') x", wantErr: ErrQuerySyntax{Query: "seriesByTag('a=b') x", Offset: 19, Reason: "unexpected symbols after ')'"}},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.query, func(t *testing.T) {
+			got, err := SeriesByTagArgs(tt.query)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("SeriesByTagArgs(%q) error = %v", tt.query, err)
+				}
+				if !cmp.Equal(tt.want, got) {
+					t.Errorf("SeriesByTagArgs(%q) = %s", tt.query, cmp.Diff(tt.want, got))
+				}
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseSeriesByTag_Escape(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantQuery  string
+		matchPaths []string
+		missPaths  []string
+	}{
+		{
+			query:      `seriesByTag('name=a', "b=it's")`,
+			wantQuery:  `seriesByTag('__name__=a','b=it\'s')`,
+			matchPaths: []string{"a?b=it%27s"},
+			missPaths:  []string{"a?b=its"},
+		},
+		{
+			query:      `seriesByTag('name=a', 'b=c\\')`,
+			wantQuery:  `seriesByTag('__name__=a','b=c\\')`,
+			matchPaths: []string{"a?b=c%5C"},
+			missPaths:  []string{"a?b=c"},
+		},
+		{
+			query:      `seriesByTag('name=a', 'b=~c\\\'')`,
+			wantQuery:  `seriesByTag('__name__=a','b=~c\\\'')`,
+			matchPaths: []string{"a?b=c%27"},
+			missPaths:  []string{"a?b=c"},
+		},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.query, func(t *testing.T) {
+			terms, err := ParseSeriesByTag(tt.query)
+			if err != nil {
+				t.Fatalf("ParseSeriesByTag(%q) error = %v", tt.query, err)
+			}
+			assert.Equal(t, tt.wantQuery, terms.String())
+			verifyTaggedTermList(t, tt.matchPaths, tt.missPaths, terms)
+
+			// round-trip
+			terms2, err := ParseSeriesByTag(tt.wantQuery)
+			if err != nil {
+				t.Fatalf("ParseSeriesByTag(%q) error = %v", tt.wantQuery, err)
+			}
+			if !cmp.Equal(terms, terms2, cmpTransform) {
+				t.Errorf("ParseSeriesByTag(%q) round-trip = %s", tt.wantQuery, cmp.Diff(terms, terms2, cmpTransform))
+			}
+		})
+	}
+}
+
+func TestParseSeriesByTag_Many(t *testing.T) {
+	args := make([]string, 200)
+	for i := range args {
+		args[i] = "'k" + strconv.Itoa(i+1000) + "=v'"
+	}
+	query := "seriesByTag(" + strings.Join(args, ",") + ")"
+	terms, err := ParseSeriesByTag(query)
+	if err != nil {
+		t.Fatalf("ParseSeriesByTag() error = %v", err)
+	}
+	assert.Equal(t, 200, len(terms))
+	assert.Equal(t, query, terms.String())
+}
+
+func BenchmarkSeriesByTagArgs(b *testing.B) {
+	query := `seriesByTag('name=a', 'b=c', 'd=~e(a|z)\.a', "f!=it\'s")`
+	for i := 0; i < b.N; i++ {
+		_, err := SeriesByTagArgs(query)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package gtags
 
 import (
+	"strconv"
 	"testing"
 	"time"
 
@@ -25,7 +26,7 @@ func BenchmarkBatchHuge_Tree_ByTags(b *testing.B) {
 				b.Fatal(err)
 			}
 		}
-		first := items.MinStore{-1}
+		first := items.MinStore{Min: -1}
 		for j := 0; j < len(pathsBatchHugeMoira); j++ {
 			first.Init()
 			tags, _ := PathTags(pathsBatchHugeMoira[j])
@@ -82,6 +83,62 @@ func BenchmarkBatchHuge_Tree_Precompiled(b *testing.B) {
 	b.ReportMetric(float64(b.N*len(pathsBatchHugeMoira))/d.Seconds(), "match/s")
 }
 
+func BenchmarkBatchHuge_Tree_Precompiled_Selectivity(b *testing.B) {
+	pathsBatchHugeMoira := generateTaggedMetrics(termsBatchHugeMoira, len(termsBatchHugeMoira))
+
+	w := NewTree()
+	w.Order = OrderBySelectivity
+	for j := 0; j < len(queriesBatchHugeMoira); j++ {
+		_, _, err := w.Add(queriesBatchHugeMoira[j], j)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	start := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var store items.AllStore
+		store.Init()
+		for j := 0; j < len(pathsBatchHugeMoira); j++ {
+			store.Init()
+			tags, _ := PathTags(pathsBatchHugeMoira[j])
+			_ = w.MatchByTags(tags, &store)
+		}
+	}
+	b.StopTimer()
+	d := time.Since(start) // TODO: Golang 1.20 has b.Elapsed() method
+	b.ReportMetric(float64(b.N*len(pathsBatchHugeMoira))/d.Seconds(), "match/s")
+}
+
+func BenchmarkBatchHuge_Tree_ByMap_Precompiled_Selectivity(b *testing.B) {
+	pathsBatchHugeMoira := generateTaggedMetrics(termsBatchHugeMoira, len(termsBatchHugeMoira))
+
+	w := NewTree()
+	w.Order = OrderBySelectivity
+	for j := 0; j < len(queriesBatchHugeMoira); j++ {
+		_, _, err := w.Add(queriesBatchHugeMoira[j], j)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	start := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var store items.AllStore
+		store.Init()
+		for j := 0; j < len(pathsBatchHugeMoira); j++ {
+			store.Init()
+			tags, _ := PathTagsMap(pathsBatchHugeMoira[j])
+			_ = w.MatchByTagsMap(tags, &store)
+		}
+	}
+	b.StopTimer()
+	d := time.Since(start) // TODO: Golang 1.20 has b.Elapsed() method
+	b.ReportMetric(float64(b.N*len(pathsBatchHugeMoira))/d.Seconds(), "match/s")
+}
+
 func BenchmarkBatchHuge_Tree_ByMap_Precompiled(b *testing.B) {
 	pathsBatchHugeMoira := generateTaggedMetrics(termsBatchHugeMoira, len(termsBatchHugeMoira))
 
@@ -124,7 +181,7 @@ func BenchmarkBatchHuge_Tree_ByMap_PrecompiledB(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		tags := make(map[string]string)
-		store := items.MinStore{-1}
+		store := items.MinStore{Min: -1}
 		for j := 0; j < len(pathsBatchHugeMoira); j++ {
 			store.Init()
 			_ = PathTagsMapB(pathsBatchHugeMoira[j], tags)
@@ -231,3 +288,96 @@ func BenchmarkBatchHuge_GTag_Prealloc(b *testing.B) {
 	d := time.Since(start) // TODO: Golang 1.20 has b.Elapsed() method
 	b.ReportMetric(float64(b.N*len(pathsBatchHugeMoira))/d.Seconds(), "match/s")
 }
+
+func BenchmarkBatchHuge_Tree_MatchBatch(b *testing.B) {
+	pathsBatchHugeMoira := generateTaggedMetrics(termsBatchHugeMoira, len(termsBatchHugeMoira))
+
+	w := NewTree()
+	for j := 0; j < len(queriesBatchHugeMoira); j++ {
+		_, _, err := w.Add(queriesBatchHugeMoira[j], j)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	start := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = w.MatchBatch(pathsBatchHugeMoira, 0)
+	}
+	b.StopTimer()
+	d := time.Since(start) // TODO: Golang 1.20 has b.Elapsed() method
+	b.ReportMetric(float64(b.N*len(pathsBatchHugeMoira))/d.Seconds(), "match/s")
+}
+
+func BenchmarkBatchHuge_Tree_MatchBatchByTags(b *testing.B) {
+	pathsBatchHugeMoira := generateTaggedMetrics(termsBatchHugeMoira, len(termsBatchHugeMoira))
+
+	w := NewTree()
+	for j := 0; j < len(queriesBatchHugeMoira); j++ {
+		_, _, err := w.Add(queriesBatchHugeMoira[j], j)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	tagsList := tagsList(pathsBatchHugeMoira)
+
+	start := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = w.MatchBatchByTags(tagsList, 0)
+	}
+	b.StopTimer()
+	d := time.Since(start) // TODO: Golang 1.20 has b.Elapsed() method
+	b.ReportMetric(float64(b.N*len(pathsBatchHugeMoira))/d.Seconds(), "match/s")
+}
+
+// queries share keys and differ only in literal values (full match lookup)
+func literalQueries(n int) (queries, paths []string) {
+	queries = make([]string, n)
+	paths = make([]string, 0, n/10)
+	for i := 0; i < n; i++ {
+		host := "host" + strconv.Itoa(i)
+		queries[i] = "seriesByTag('name=cpu.load','dc=dc" + strconv.Itoa(i%4) + "','host=" + host + "')"
+		if i%10 == 0 {
+			paths = append(paths, "cpu.load?dc=dc"+strconv.Itoa(i%4)+"&host="+host+"&env=prod")
+		}
+	}
+	return
+}
+
+func BenchmarkBatchHuge_Tree_Literal_Precompiled(b *testing.B) {
+	queries, paths := literalQueries(10000)
+	w := NewTree()
+	for j := 0; j < len(queries); j++ {
+		_, _, err := w.Add(queries[j], j)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	tagsList := make([][]Tag, len(paths))
+	for j := 0; j < len(paths); j++ {
+		tags, err := PathTags(paths[j])
+		if err != nil {
+			b.Fatal(err)
+		}
+		SortTags(tags)
+		tagsList[j] = tags
+	}
+
+	start := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		first := items.MinStore{Min: -1}
+		for j := 0; j < len(tagsList); j++ {
+			first.Init()
+			if w.MatchByTags(tagsList[j], &first) == 0 {
+				b.Fatal(paths[j])
+			}
+		}
+	}
+	b.StopTimer()
+	d := time.Since(start) // TODO: Golang 1.20 has b.Elapsed() method
+	b.ReportMetric(float64(b.N*len(tagsList))/d.Seconds(), "match/s")
+}
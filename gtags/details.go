@@ -0,0 +1,128 @@
+package gtags
+
+// TermMatch is a term match details
+type TermMatch struct {
+	Term   *TaggedTerm
+	Key    string   // matched tag key (differ from term key for wildcard/regexp key)
+	Value  string   // matched tag value (empty if absent)
+	Absent bool     // term is matched with absent (or empty) tag, like tag!=x
+	Groups []string // regexp submatches for =~ term with capture groups
+}
+
+// QueryMatch is a matched query details
+type QueryMatch struct {
+	Query string
+	Index int
+	Terms []TermMatch // terms match details (in normalized query order)
+}
+
+// newTermMatch return details for matched term
+func newTermMatch(term *TaggedTerm, key, v string) TermMatch {
+	d := TermMatch{Term: term, Key: key, Value: v, Absent: v == ""}
+	if term.Op == TaggedTermMatch && v != "" && term.Re.NumSubexp() > 0 {
+		if groups := term.Re.FindStringSubmatch(v); len(groups) > 1 {
+			d.Groups = groups[1:]
+		}
+	}
+	return d
+}
+
+// keyTermMatch return details for matched wildcard key term (first tag, matched by positive term or with matched key for negative term)
+func keyTermMatch(term *TaggedTerm, tags []Tag) TermMatch {
+	for i := range tags {
+		if tags[i].Value == "" || !term.MatchKey(tags[i].Key) {
+			continue
+		}
+		if term.negative() || term.Match(tags[i].Value) {
+			return newTermMatch(term, tags[i].Key, tags[i].Value)
+		}
+	}
+	return TermMatch{Term: term, Absent: true}
+}
+
+// MatchDetailsByTags match against sorted tags slice (like MatchByTags) and return terms match details
+func (terms TaggedTermList) MatchDetailsByTags(tags []Tag) (details []TermMatch, matched bool) {
+	if !terms.MatchByTags(tags) {
+		return nil, false
+	}
+	details = make([]TermMatch, len(terms))
+	for n := range terms {
+		term := &terms[n]
+		if term.IsWildcardKey() {
+			details[n] = keyTermMatch(term, tags)
+		} else if i := find(tags, term.Key, 0); i == -1 {
+			details[n] = TermMatch{Term: term, Key: term.Key, Absent: true}
+		} else {
+			details[n] = newTermMatch(term, term.Key, tags[i].Value)
+		}
+	}
+	return details, true
+}
+
+// MatchDetailsByTags match sorted tags (like MatchByTags) and append matched queries details to dst
+func (gtree *GTagsTree) MatchDetailsByTags(tags []Tag, dst []QueryMatch) []QueryMatch {
+	if gtree.Terminate {
+		dst = append(dst, QueryMatch{Query: gtree.Terminated.Query, Index: gtree.Terminated.Index})
+	}
+	memo := gtree.getMemo()
+	dst = gtree.Root.matchDetailsByTags(tags, make([]TermMatch, 0, 8), dst, memo)
+	gtree.memoPool.Put(memo)
+	return dst
+}
+
+// terminated append query details, if item is terminated
+func (item *TaggedItem) terminated(path []TermMatch, dst []QueryMatch) []QueryMatch {
+	if item.Terminate {
+		terms := make([]TermMatch, len(path))
+		copy(terms, path)
+		// tree can be builded in selectivity order
+		sortTermMatches(terms)
+		dst = append(dst, QueryMatch{Query: item.Query, Index: item.Index, Terms: terms})
+	}
+	return dst
+}
+
+func (item *TaggedItem) matchDetailsByTags(tags []Tag, path []TermMatch, dst []QueryMatch, memo *termsMemo) []QueryMatch {
+	matchPos := 0
+
+	for i := 0; i < len(item.Items); i++ {
+		// absent tag is an empty value (graphite semantics)
+		var v string
+		key := item.Items[i].Key
+		if n := find(tags, key, matchPos); n != -1 {
+			matchPos = n
+			v = tags[n].Value
+		}
+		if child, ok := item.Items[i].MatchedMap[v]; ok {
+			p := append(path, newTermMatch(child.Term, key, v))
+			dst = child.terminated(p, dst)
+			dst = child.matchDetailsByTags(tags, p, dst, memo)
+		}
+		for _, child := range item.Items[i].Matched {
+			if !memo.match(child.Term, v) {
+				continue
+			}
+			p := append(path, newTermMatch(child.Term, key, v))
+			dst = child.terminated(p, dst)
+			dst = child.matchDetailsByTags(tags, p, dst, memo)
+		}
+		for _, child := range item.Items[i].NotMatched {
+			if !memo.match(child.Term, v) {
+				continue
+			}
+			p := append(path, newTermMatch(child.Term, key, v))
+			dst = child.terminated(p, dst)
+			dst = child.matchDetailsByTags(tags, p, dst, memo)
+		}
+	}
+	for _, child := range item.KeyItems {
+		if !memo.matchKeyTags(child.Term, tags) {
+			continue
+		}
+		p := append(path, keyTermMatch(child.Term, tags))
+		dst = child.terminated(p, dst)
+		dst = child.matchDetailsByTags(tags, p, dst, memo)
+	}
+
+	return dst
+}
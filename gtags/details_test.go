@@ -0,0 +1,142 @@
+package gtags
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/msaf1980/go-matcher/pkg/items"
+	"github.com/stretchr/testify/assert"
+)
+
+type termMatchStr struct {
+	Term   string
+	Key    string
+	Value  string
+	Absent bool
+	Groups []string
+}
+
+func stringTermMatches(details []TermMatch) []termMatchStr {
+	if details == nil {
+		return nil
+	}
+	s := make([]termMatchStr, len(details))
+	for i, d := range details {
+		s[i] = termMatchStr{Term: d.Term.String(), Key: d.Key, Value: d.Value, Absent: d.Absent, Groups: d.Groups}
+	}
+	return s
+}
+
+func TestTaggedTermList_MatchDetailsByTags(t *testing.T) {
+	tests := []struct {
+		query   string
+		path    string
+		want    []termMatchStr
+		wantNot bool
+	}{
+		{
+			query: `seriesByTag('name=cpu', 'host=~^(h)(\d+)$', 'dc!=x', 'env!=prod')`,
+			path:  "cpu?dc=y&host=h12",
+			want: []termMatchStr{
+				{Term: "__name__=cpu", Key: "__name__", Value: "cpu"},
+				{Term: "dc!=x", Key: "dc", Value: "y"},
+				{Term: "env!=prod", Key: "env", Absent: true},
+				{Term: `host=~^(h)(\d+)$`, Key: "host", Value: "h12", Groups: []string{"h", "12"}},
+			},
+		},
+		{
+			query: "seriesByTag('name=cpu', 'k8s_*=~^p', '~^debug!=~.*', 'shard>1')",
+			path:  "cpu?k8s_env=dev&k8s_ns=prod&shard=2",
+			want: []termMatchStr{
+				{Term: "__name__=cpu", Key: "__name__", Value: "cpu"},
+				{Term: "k8s_*=~^p", Key: "k8s_ns", Value: "prod"},
+				{Term: "shard>1", Key: "shard", Value: "2"},
+				{Term: "~^debug!=~.*", Absent: true},
+			},
+		},
+		{
+			query:   "seriesByTag('name=cpu', 'dc!=x')",
+			path:    "cpu?dc=x",
+			wantNot: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query+"#"+tt.path, func(t *testing.T) {
+			terms, err := ParseSeriesByTag(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tags, err := PathTags(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			details, ok := terms.MatchDetailsByTags(tags)
+			assert.Equal(t, !tt.wantNot, ok)
+			assert.Equal(t, tt.want, stringTermMatches(details))
+			for i := range details {
+				assert.Same(t, &terms[i], details[i].Term)
+			}
+
+			// tree details must be the same
+			gtree := NewTree()
+			if _, _, err = gtree.Add(tt.query, 1); err != nil {
+				t.Fatal(err)
+			}
+			got := gtree.MatchDetailsByTags(tags, nil)
+			if tt.wantNot {
+				assert.Empty(t, got)
+			} else if assert.Equal(t, 1, len(got)) {
+				assert.Equal(t, terms.String(), got[0].Query)
+				assert.Equal(t, 1, got[0].Index)
+				assert.Equal(t, tt.want, stringTermMatches(got[0].Terms))
+			}
+		})
+	}
+}
+
+func TestGTagsTree_MatchDetailsByTags(t *testing.T) {
+	gtree := NewTree()
+	queries := []string{
+		"seriesByTag('name=cpu', 'host=~^h(\\d+)$')",
+		"seriesByTag('name=cpu', 'host=~^h(\\d+)$', 'dc=a')",
+		"seriesByTag('name=cpu', 'dc!=b')",
+		"seriesByTag('name=mem')",
+	}
+	for i, query := range queries {
+		if _, _, err := gtree.Add(query, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tags, err := PathTags("cpu?dc=a&host=h1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := gtree.MatchDetailsByTags(tags, nil)
+	sort.Slice(got, func(i, j int) bool { return got[i].Index < got[j].Index })
+
+	if len(got) != 3 {
+		t.Fatalf("GTagsTree.MatchDetailsByTags() = %d queries, want 3", len(got))
+	}
+	assert.Equal(t, []int{0, 1, 2}, []int{got[0].Index, got[1].Index, got[2].Index})
+	assert.Equal(t, []termMatchStr{
+		{Term: "__name__=cpu", Key: "__name__", Value: "cpu"},
+		{Term: `host=~^h(\d+)$`, Key: "host", Value: "h1", Groups: []string{"1"}},
+	}, stringTermMatches(got[0].Terms))
+	assert.Equal(t, []termMatchStr{
+		{Term: "__name__=cpu", Key: "__name__", Value: "cpu"},
+		{Term: "dc=a", Key: "dc", Value: "a"},
+		{Term: `host=~^h(\d+)$`, Key: "host", Value: "h1", Groups: []string{"1"}},
+	}, stringTermMatches(got[1].Terms))
+	assert.Equal(t, []termMatchStr{
+		{Term: "__name__=cpu", Key: "__name__", Value: "cpu"},
+		{Term: "dc!=b", Key: "dc", Value: "a"},
+	}, stringTermMatches(got[2].Terms))
+
+	// interned terms are shared
+	assert.Same(t, got[0].Terms[1].Term, got[1].Terms[2].Term)
+
+	// the same queries as MatchByTags
+	var store items.IndexStore
+	n := gtree.MatchByTags(tags, &store)
+	assert.Equal(t, n, len(got))
+}
@@ -0,0 +1,142 @@
+package gtags
+
+import (
+	"strings"
+)
+
+// Dialect is a seriesByTag backend compatibility mode
+type Dialect int8
+
+const (
+	// DialectGraphiteClickHouse is a graphite-clickhouse compatible dialect (=~ regexp is unanchored, like ClickHouse match)
+	DialectGraphiteClickHouse Dialect = iota
+	// DialectGraphiteWeb is a graphite-web compatible dialect (=~ regexp is anchored at start, like python re.match)
+	DialectGraphiteWeb
+	// DialectPrometheus is a Prometheus compatible dialect (=~ regexp is anchored at both ends)
+	DialectPrometheus
+)
+
+// Dialect options, can be combined with dialect (like DialectGraphiteWeb | DialectLiteralEq)
+const (
+	// DialectLiteralEq is a dialect option: = and != values are compared as strings, not as globs (like in graphite-web)
+	DialectLiteralEq Dialect = 1 << (iota + 4)
+	// DialectNameGGlob is a dialect option: __name__ globs are dot-aware (gglob semantics, * and ? don't match dot)
+	DialectNameGGlob
+
+	dialectMask = DialectLiteralEq - 1
+)
+
+// DialectGraphiteWebStrict is a strict graphite-web compatible dialect (= is literal, =~ regexp is anchored at start)
+const DialectGraphiteWebStrict = DialectGraphiteWeb | DialectLiteralEq
+
+var (
+	stringsDialect        = []string{"graphite-clickhouse", "graphite-web", "prometheus"}
+	stringsDialectOptions = []string{"literal-eq", "name-gglob"}
+)
+
+// base return dialect without options
+func (d Dialect) base() Dialect {
+	return d & dialectMask
+}
+
+func (d Dialect) String() string {
+	s := stringsDialect[d.base()]
+	for i, opt := range stringsDialectOptions {
+		if d&(DialectLiteralEq<<i) != 0 {
+			s += "+" + opt
+		}
+	}
+	return s
+}
+
+// ParseDialect parse dialect name (graphite-clickhouse, graphite-web or prometheus) with optional options
+// (like graphite-web+literal-eq or graphite-clickhouse+name-gglob)
+func ParseDialect(s string) (Dialect, error) {
+	names := strings.Split(s, "+")
+	d := Dialect(-1)
+	for i, name := range stringsDialect {
+		if names[0] == name {
+			d = Dialect(i)
+			break
+		}
+	}
+	if d == -1 {
+		return DialectGraphiteClickHouse, ErrDialectInvalid{s}
+	}
+	for _, opt := range names[1:] {
+		found := false
+		for i, name := range stringsDialectOptions {
+			if opt == name {
+				d |= DialectLiteralEq << i
+				found = true
+				break
+			}
+		}
+		if !found {
+			return DialectGraphiteClickHouse, ErrDialectInvalid{s}
+		}
+	}
+	return d, nil
+}
+
+// anchorRegexp rewrite regexp for unanchored match with dialect anchoring semantics
+// (already anchored regexp is returned unchanged, so rewrite is idempotent)
+func (d Dialect) anchorRegexp(re string) string {
+	switch d.base() {
+	case DialectGraphiteWeb:
+		if strings.HasPrefix(re, "^") && !strings.Contains(re, "|") {
+			return re
+		}
+		if end := anchoredGroupEnd(re); end == len(re)-1 || (end == len(re)-2 && re[len(re)-1] == '$') {
+			return re
+		}
+		return "^(?:" + re + ")"
+	case DialectPrometheus:
+		if strings.HasPrefix(re, "^") && strings.HasSuffix(re, "$") && !strings.HasSuffix(re, `\$`) && !strings.Contains(re, "|") {
+			return re
+		}
+		if end := anchoredGroupEnd(re); end == len(re)-2 && re[len(re)-1] == '$' {
+			return re
+		}
+		return "^(?:" + re + ")$"
+	default:
+		return re
+	}
+}
+
+// anchoredGroupEnd return close paren position for regexp started with ^(?: group or -1
+func anchoredGroupEnd(re string) int {
+	if !strings.HasPrefix(re, "^(?:") {
+		return -1
+	}
+	depth := 0
+	for i := 1; i < len(re); i++ {
+		switch re[i] {
+		case '\\':
+			i++
+		case '[':
+			// skip char class, ] is literal at class start
+			i++
+			if i < len(re) && re[i] == '^' {
+				i++
+			}
+			if i < len(re) && re[i] == ']' {
+				i++
+			}
+			for i < len(re) && re[i] != ']' {
+				if re[i] == '\\' {
+					i++
+				}
+				i++
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
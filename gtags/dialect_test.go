@@ -0,0 +1,308 @@
+package gtags
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/msaf1980/go-matcher/pkg/items"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDialect(t *testing.T) {
+	for _, d := range []Dialect{
+		DialectGraphiteClickHouse, DialectGraphiteWeb, DialectPrometheus,
+		DialectGraphiteWebStrict, DialectGraphiteClickHouse | DialectNameGGlob,
+		DialectPrometheus | DialectLiteralEq | DialectNameGGlob,
+	} {
+		got, err := ParseDialect(d.String())
+		assert.NoError(t, err)
+		assert.Equal(t, d, got)
+	}
+	assert.Equal(t, "graphite-web+literal-eq", DialectGraphiteWebStrict.String())
+	assert.Equal(t, "graphite-clickhouse+literal-eq+name-gglob", (DialectLiteralEq | DialectNameGGlob).String())
+
+	got, err := ParseDialect("graphite-clickhouse+name-gglob+literal-eq")
+	assert.NoError(t, err)
+	assert.Equal(t, DialectGraphiteClickHouse|DialectLiteralEq|DialectNameGGlob, got)
+
+	for _, s := range []string{"graphite", "graphite-web+", "graphite-web+literal"} {
+		_, err = ParseDialect(s)
+		assert.Equal(t, ErrDialectInvalid{s}, err)
+	}
+}
+
+func TestParseSeriesByTagDialect(t *testing.T) {
+	tests := []struct {
+		query      string
+		dialect    Dialect
+		wantQuery  string
+		matchPaths []string
+		missPaths  []string
+	}{
+		{
+			query:      `seriesByTag('name=a', 'b=~c(a|z)')`,
+			dialect:    DialectGraphiteClickHouse,
+			wantQuery:  `seriesByTag('__name__=a','b=~c(a|z)')`,
+			matchPaths: []string{"a?b=ca", "a?b=ca1", "a?b=1ca", "a?b=1ca1"},
+			missPaths:  []string{"a?b=c", "a?c=ca"},
+		},
+		{
+			query:      `seriesByTag('name=a', 'b=~c(a|z)')`,
+			dialect:    DialectGraphiteWeb,
+			wantQuery:  `seriesByTag('__name__=a','b=~^(?:c(a|z))')`,
+			matchPaths: []string{"a?b=ca", "a?b=ca1"},
+			missPaths:  []string{"a?b=1ca", "a?b=1ca1", "a?b=c"},
+		},
+		{
+			query:      `seriesByTag('name=a', 'b=~^c')`,
+			dialect:    DialectGraphiteWeb,
+			wantQuery:  `seriesByTag('__name__=a','b=~^c')`,
+			matchPaths: []string{"a?b=ca", "a?b=c"},
+			missPaths:  []string{"a?b=1c"},
+		},
+		{
+			query:      `seriesByTag('name=a', 'b=~ca|z')`,
+			dialect:    DialectGraphiteWeb,
+			wantQuery:  `seriesByTag('__name__=a','b=~^(?:ca|z)')`,
+			matchPaths: []string{"a?b=ca", "a?b=za"},
+			missPaths:  []string{"a?b=az", "a?b=1ca"},
+		},
+		{
+			query:      `seriesByTag('name=a', 'b=~c(a|z)')`,
+			dialect:    DialectPrometheus,
+			wantQuery:  `seriesByTag('__name__=a','b=~^(?:c(a|z))$')`,
+			matchPaths: []string{"a?b=ca", "a?b=cz"},
+			missPaths:  []string{"a?b=ca1", "a?b=1ca", "a?b=c"},
+		},
+		{
+			query:      `seriesByTag('name=a*', 'b!=c?', 'c=d')`,
+			dialect:    DialectGraphiteWebStrict,
+			wantQuery:  `seriesByTag('__name__=a*','b!=c?','c=d')`,
+			matchPaths: []string{"a*?b=c&c=d", "a*?b=cd&c=d", "a*?c=d"},
+			missPaths:  []string{"ab?b=c&c=d", "a*?b=c?&c=d", "a*?b=c&c=e"},
+		},
+		{
+			query:      `seriesByTag('name=a.*', 'b=c{a,b}')`,
+			dialect:    DialectGraphiteClickHouse | DialectLiteralEq | DialectNameGGlob,
+			wantQuery:  `seriesByTag('__name__=a.*','b=c{a,b}')`,
+			matchPaths: []string{"a.*?b=c{a,b}"},
+			missPaths:  []string{"a.b?b=c{a,b}", "a.*?b=ca"},
+		},
+		{
+			query:      `seriesByTag('name=a', 'b!=~^ca$')`,
+			dialect:    DialectPrometheus,
+			wantQuery:  `seriesByTag('__name__=a','b!=~^ca$')`,
+			matchPaths: []string{"a?b=ca1", "a?b=c", "a?c=ca"},
+			missPaths:  []string{"a?b=ca"},
+		},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.dialect.String()+"#"+tt.query, func(t *testing.T) {
+			terms, err := ParseSeriesByTagDialect(tt.query, tt.dialect)
+			if err != nil {
+				t.Fatalf("ParseSeriesByTagDialect(%q) error = %v", tt.query, err)
+			}
+			assert.Equal(t, tt.wantQuery, terms.String())
+			verifyTaggedTermList(t, tt.matchPaths, tt.missPaths, terms)
+
+			// parse of normalized query with the same dialect must be idempotent
+			terms, err = ParseSeriesByTagDialect(tt.wantQuery, tt.dialect)
+			if err != nil {
+				t.Fatalf("ParseSeriesByTagDialect(%q) error = %v", tt.wantQuery, err)
+			}
+			assert.Equal(t, tt.wantQuery, terms.String())
+			verifyTaggedTermList(t, tt.matchPaths, tt.missPaths, terms)
+
+			gtree := NewTreeDialect(tt.dialect)
+			normalized, _, err := gtree.Add(tt.query, 0)
+			if err != nil {
+				t.Fatalf("GTagsTree.Add(%q) error = %v", tt.query, err)
+			}
+			assert.Equal(t, tt.wantQuery, normalized)
+			match := make(map[string][]string)
+			for _, path := range tt.matchPaths {
+				match[path] = []string{tt.wantQuery}
+			}
+			for _, path := range tt.missPaths {
+				match[path] = []string{}
+			}
+			verifyGTagsTree(t, []string{tt.query}, match, gtree)
+		})
+	}
+}
+
+func TestParseSeriesByTagDialect_NameGGlob(t *testing.T) {
+	tests := []struct {
+		query      string
+		dialect    Dialect
+		wantQuery  string
+		matchPaths []string
+		missPaths  []string
+	}{
+		{
+			query:      `seriesByTag('name=a.*.c', 'b=c*')`,
+			dialect:    DialectGraphiteClickHouse | DialectNameGGlob,
+			wantQuery:  `seriesByTag('__name__=a.*.c','b=c*')`,
+			matchPaths: []string{"a.b.c?b=c", "a.bd.c?b=cd"},
+			missPaths:  []string{"a.b.d.c?b=c", "a.b.c?b=d", "a..c?b=c"},
+		},
+		{
+			query:      `seriesByTag('name!=a.b?', 'b=c')`,
+			dialect:    DialectGraphiteWeb | DialectNameGGlob,
+			wantQuery:  `seriesByTag('__name__!=a.b?','b=c')`,
+			matchPaths: []string{"a.b.c?b=c", "a.b?b=c", "a.bcd?b=c"},
+			missPaths:  []string{"a.bc?b=c", "a.b.c?b=d"},
+		},
+		{
+			// b is not a __name__
+			query:      `seriesByTag('name=a', 'b=c.*')`,
+			dialect:    DialectGraphiteClickHouse | DialectNameGGlob,
+			wantQuery:  `seriesByTag('__name__=a','b=c.*')`,
+			matchPaths: []string{"a?b=c.d", "a?b=c.d.e"},
+			missPaths:  []string{"a?b=cd"},
+		},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.dialect.String()+"#"+tt.query, func(t *testing.T) {
+			terms, err := ParseSeriesByTagDialect(tt.query, tt.dialect)
+			if err != nil {
+				t.Fatalf("ParseSeriesByTagDialect(%q) error = %v", tt.query, err)
+			}
+			assert.Equal(t, tt.wantQuery, terms.String())
+			verifyTaggedTermList(t, tt.matchPaths, tt.missPaths, terms)
+
+			gtree := NewTreeDialect(tt.dialect)
+			normalized, _, err := gtree.Add(tt.query, 0)
+			if err != nil {
+				t.Fatalf("GTagsTree.Add(%q) error = %v", tt.query, err)
+			}
+			assert.Equal(t, tt.wantQuery, normalized)
+			match := make(map[string][]string)
+			for _, path := range tt.matchPaths {
+				match[path] = []string{tt.wantQuery}
+			}
+			for _, path := range tt.missPaths {
+				match[path] = []string{}
+			}
+			verifyGTagsTree(t, []string{tt.query}, match, gtree)
+		})
+	}
+}
+
+func TestParseSeriesByTagDialect_Idempotent(t *testing.T) {
+	queries := []string{
+		`seriesByTag('name=a','b=~b|c')`,
+		`seriesByTag('name=a','b!=~^b|c$')`,
+		`seriesByTag('name=a','b=~(b|c)d')`,
+		`seriesByTag('name=a','b=~[)(]|c')`,
+		`seriesByTag('name=a','b=~\(b|c\)')`,
+		`seriesByTag('name=a','b=~^(?:b)|(?:c)')`,
+		`seriesByTag('name=a','~b|c=~d|e')`,
+		`seriesByTag('name=a','b=c*','c!=d|e')`,
+		`seriesByTag('name=a','b!=x|y*')`,
+	}
+	for _, dialect := range []Dialect{
+		DialectGraphiteClickHouse, DialectGraphiteWeb, DialectPrometheus,
+		DialectGraphiteClickHouse | DialectLiteralEq, DialectGraphiteWebStrict, DialectPrometheus | DialectLiteralEq,
+		DialectGraphiteWeb | DialectNameGGlob,
+	} {
+		for _, query := range queries {
+			t.Run(dialect.String()+"#"+query, func(t *testing.T) {
+				terms, err := ParseSeriesByTagDialect(query, dialect)
+				if err != nil {
+					t.Fatalf("ParseSeriesByTagDialect(%q) error = %v", query, err)
+				}
+				normalized := terms.String()
+				for i := 0; i < 2; i++ {
+					terms, err = ParseSeriesByTagDialect(normalized, dialect)
+					if err != nil {
+						t.Fatalf("ParseSeriesByTagDialect(%q) error = %v", normalized, err)
+					}
+					assert.Equal(t, normalized, terms.String())
+				}
+
+				gtree := NewTreeDialect(dialect)
+				got, _, err := gtree.Add(normalized, 0)
+				if err != nil {
+					t.Fatalf("GTagsTree.Add(%q) error = %v", normalized, err)
+				}
+				assert.Equal(t, normalized, got)
+			})
+		}
+	}
+}
+
+func TestGTagsTree_LiteralEq(t *testing.T) {
+	gtree := NewTreeDialect(DialectGraphiteWebStrict)
+	gtree.Validate = ValidateErrors
+	queries := []string{
+		"seriesByTag('name=a', 'b=c*')",
+		"seriesByTag('name=a', 'b!=x|y*')",
+	}
+	for i, query := range queries {
+		if _, _, err := gtree.Add(query, i); err != nil {
+			t.Fatalf("GTagsTree.Add(%q) error = %v", query, err)
+		}
+	}
+	// literal values are compared as strings
+	a := gtree.Root.Items[0].MatchedMap["a"]
+	if assert.NotNil(t, a) {
+		assert.NotNil(t, a.Items[0].MatchedMap["c*"])
+		if assert.Equal(t, 1, len(a.Items[0].NotMatched)) {
+			term := a.Items[0].NotMatched[0].Term
+			assert.Equal(t, TaggedTermNe, term.Op)
+			assert.False(t, term.HasWildcard)
+			assert.Equal(t, "b!=x|y*", term.String())
+		}
+	}
+
+	for path, want := range map[string][]int{
+		"a?b=c*":    {0, 1},
+		"a?b=cd":    {1},
+		"a?b=x|y*":  nil,
+		"a?b=x|yz":  {1},
+		"a?c=x|y*":  {1},
+		"ab?b=x|yz": nil,
+	} {
+		tags, err := PathTags(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var store items.IndexStore
+		gtree.MatchByTags(tags, &store)
+		sort.Ints(store.N)
+		assert.Equal(t, want, store.N, path)
+
+		details := gtree.MatchDetailsByTags(tags, nil)
+		for _, m := range details {
+			for _, d := range m.Terms {
+				assert.NotEqual(t, TaggedTermMatch, d.Term.Op, d.Term.String())
+				assert.NotEqual(t, TaggedTermNotMatch, d.Term.Op, d.Term.String())
+			}
+		}
+	}
+
+	// literals conflict
+	_, _, err := gtree.Add("seriesByTag('name=a', 'b=c*', 'b=d*')", 2)
+	assert.Equal(t, ErrTermsConflict{"b=c*", "b=d*"}, err)
+
+	// literal and glob terms with the same string are not interned together
+	terms, err := ParseSeriesByTag("seriesByTag('name=a', 'b=c*', 'd=e')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = gtree.AddTerms(terms, 2); err != nil {
+		t.Fatalf("GTagsTree.AddTerms(%q) error = %v", terms.String(), err)
+	}
+	assert.True(t, gtree.Terms["b=c*"].HasWildcard)
+	assert.False(t, gtree.Terms["b=c*"+termKindLiteral].HasWildcard)
+	tags, err := PathTags("a?b=cd&d=e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var store items.IndexStore
+	gtree.MatchByTags(tags, &store)
+	sort.Ints(store.N)
+	assert.Equal(t, []int{1, 2}, store.N)
+}
@@ -1,5 +1,7 @@
 package gtags
 
+import "strconv"
+
 type ErrQueryInvalid struct {
 	Query string
 }
@@ -40,3 +42,89 @@ type ErrPathInvalid struct {
 func (e ErrPathInvalid) Error() string {
 	return "invalid path: '" + e.Node + "' " + e.Reason
 }
+
+type ErrDialectInvalid struct {
+	Dialect string
+}
+
+func (e ErrDialectInvalid) Error() string {
+	return "invalid seriesByTag dialect: " + e.Dialect
+}
+
+// ErrQuerySyntax is a seriesByTag call syntax error at byte offset
+type ErrQuerySyntax struct {
+	Query  string
+	Offset int
+	Reason string
+}
+
+func (e ErrQuerySyntax) Error() string {
+	return "wrong seriesByTag call: " + e.Reason + " at offset " + strconv.Itoa(e.Offset) + ": " + e.Query
+}
+
+// ErrSelectorSyntax is a PromQL vector selector syntax error at byte offset
+type ErrSelectorSyntax struct {
+	Selector string
+	Offset   int
+	Reason   string
+}
+
+func (e ErrSelectorSyntax) Error() string {
+	return "wrong PromQL selector: " + e.Reason + " at offset " + strconv.Itoa(e.Offset) + ": " + e.Selector
+}
+
+type ErrLabelInvalid struct {
+	Label string
+}
+
+func (e ErrLabelInvalid) Error() string {
+	return "invalid label name: " + e.Label
+}
+
+// ErrTagInvalid is a tag, not allowed in graphite tagged path
+type ErrTagInvalid struct {
+	Key    string
+	Value  string
+	Reason string
+}
+
+func (e ErrTagInvalid) Error() string {
+	return "invalid tag '" + e.Key + "=" + e.Value + "': " + e.Reason
+}
+
+// ErrTermsConflict is a seriesByTag terms, which never matched together
+type ErrTermsConflict struct {
+	Term     string
+	Conflict string
+}
+
+func (e ErrTermsConflict) Error() string {
+	return "seriesByTag terms conflict: '" + e.Term + "' and '" + e.Conflict + "'"
+}
+
+// ErrNegativeOnly is a seriesByTag query without terms, which required non-empty value
+type ErrNegativeOnly struct {
+	Query string
+}
+
+func (e ErrNegativeOnly) Error() string {
+	return "seriesByTag must contain at least one term, which required non-empty value: " + e.Query
+}
+
+// ErrTermDuplicate is a duplicate seriesByTag term (warning)
+type ErrTermDuplicate struct {
+	Term string
+}
+
+func (e ErrTermDuplicate) Error() string {
+	return "duplicate seriesByTag term: " + e.Term
+}
+
+// ErrTermMatchEmpty is a positive (= or =~) seriesByTag term, which match empty value (warning)
+type ErrTermMatchEmpty struct {
+	Term string
+}
+
+func (e ErrTermMatchEmpty) Error() string {
+	return "seriesByTag term match empty value: " + e.Term
+}
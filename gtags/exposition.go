@@ -0,0 +1,143 @@
+package gtags
+
+import (
+	"strings"
+)
+
+// SortTags sort tags by key (__name__ is first), as required by GTagsTree.MatchByTags (without allocations)
+func SortTags(tags []Tag) {
+	// insertion sort, tags count is small
+	for i := 1; i < len(tags); i++ {
+		for j := i; j > 0 && tagLess(&tags[j], &tags[j-1]); j-- {
+			tags[j], tags[j-1] = tags[j-1], tags[j]
+		}
+	}
+}
+
+func tagLess(a, b *Tag) bool {
+	if a.Key == "__name__" {
+		return b.Key != "__name__"
+	} else if b.Key == "__name__" {
+		return false
+	}
+	return a.Key < b.Key
+}
+
+// unescapeLabelValue unescape Prometheus exposition label value (only \\, \" and \n escapes are valid)
+func unescapeLabelValue(s string) (string, bool) {
+	var buf strings.Builder
+	buf.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			switch s[i] {
+			case '\\', '"':
+				buf.WriteByte(s[i])
+			case 'n':
+				buf.WriteByte('\n')
+			default:
+				return "", false
+			}
+		} else {
+			buf.WriteByte(s[i])
+		}
+	}
+	return buf.String(), true
+}
+
+// PrometheusTags split Prometheus/OpenMetrics exposition series (like name{a="v1",b="v2"} value ts) into sorted Tag's slice.
+// Labels with empty values are skipped (like in Prometheus).
+func PrometheusTags(line string) (tags []Tag, err error) {
+	return PrometheusTagsB(line, make([]Tag, 0, strings.Count(line, "=")+1))
+}
+
+// PrometheusTagsB split Prometheus/OpenMetrics exposition series (like name{a="v1",b="v2"} value ts) into sorted Tag's slice,
+// tags are appended to tags[:0]. Without allocations, if tags capacity is enough and values are not escaped.
+func PrometheusTagsB(line string, tags []Tag) ([]Tag, error) {
+	tags = tags[:0]
+	pos := skipSpaces(line, 0)
+	if pos < len(line) && isMetricStart(line[pos]) {
+		start := pos
+		for pos < len(line) && isMetricChar(line[pos]) {
+			pos++
+		}
+		tags = append(tags, Tag{Key: "__name__", Value: line[start:pos]})
+		if next := skipSpaces(line, pos); next < len(line) && line[next] == '{' {
+			pos = next
+		}
+	}
+
+	if pos < len(line) && line[pos] == '{' {
+		pos = skipSpaces(line, pos+1)
+		for pos < len(line) && line[pos] != '}' {
+			start := pos
+			if !isLabelStart(line[pos]) {
+				return tags, ErrPathInvalid{line[pos:], "label name expected"}
+			}
+			for pos < len(line) && isLabelChar(line[pos]) {
+				pos++
+			}
+			key := line[start:pos]
+			pos = skipSpaces(line, pos)
+			if pos == len(line) || line[pos] != '=' {
+				return tags, ErrPathInvalid{line[start:], "not delimited with ="}
+			}
+			pos = skipSpaces(line, pos+1)
+			if pos == len(line) || line[pos] != '"' {
+				return tags, ErrPathInvalid{line[start:], "label value not quoted"}
+			}
+
+			// label value
+			pos++
+			start = pos
+			escaped := false
+			for ; pos < len(line) && line[pos] != '"'; pos++ {
+				if line[pos] == '\\' {
+					escaped = true
+					pos++
+				}
+			}
+			if pos >= len(line) {
+				return tags, ErrPathInvalid{line[start-1:], "label value not terminated"}
+			}
+			value := line[start:pos]
+			if escaped {
+				var ok bool
+				if value, ok = unescapeLabelValue(value); !ok {
+					return tags, ErrPathInvalid{line[start-1 : pos+1], "invalid escape sequence"}
+				}
+			}
+			if value != "" {
+				tags = append(tags, Tag{Key: key, Value: value})
+			}
+
+			pos = skipSpaces(line, pos+1)
+			if pos < len(line) && line[pos] == ',' {
+				pos = skipSpaces(line, pos+1)
+			} else if pos < len(line) && line[pos] != '}' {
+				return tags, ErrPathInvalid{line[pos:], "',' or '}' expected"}
+			}
+		}
+		if pos == len(line) {
+			return tags, ErrPathInvalid{line, "'}' expected"}
+		}
+		pos++
+	}
+
+	// value and timestamp are not parsed
+	if pos < len(line) && !isSpace(line[pos]) {
+		return tags, ErrPathInvalid{line[pos:], "unexpected symbols"}
+	}
+
+	SortTags(tags)
+	if len(tags) == 0 || tags[0].Key != "__name__" {
+		return tags, ErrPathInvalid{"name", "not found"}
+	}
+	for i := 1; i < len(tags); i++ {
+		if tags[i].Key == tags[i-1].Key {
+			return tags, ErrPathInvalid{tags[i].Key, "duplicate label"}
+		}
+	}
+
+	return tags, nil
+}
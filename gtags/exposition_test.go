@@ -0,0 +1,124 @@
+package gtags
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/msaf1980/go-matcher/pkg/items"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusTags(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []Tag
+		wantErr error
+	}{
+		{line: "up", want: []Tag{{Key: "__name__", Value: "up"}}},
+		{line: "up 1", want: []Tag{{Key: "__name__", Value: "up"}}},
+		{line: "up{} 1 1700000000000", want: []Tag{{Key: "__name__", Value: "up"}}},
+		{
+			line: `http_requests_total{method="post",code="200"} 1027 1395066363000`,
+			want: []Tag{{Key: "__name__", Value: "http_requests_total"}, {Key: "code", Value: "200"}, {Key: "method", Value: "post"}},
+		},
+		{
+			line: ` node:cpu { job = "a" , env="" , } 1.5e+3`,
+			want: []Tag{{Key: "__name__", Value: "node:cpu"}, {Key: "job", Value: "a"}},
+		},
+		{
+			line: `{job="a",__name__="up"}`,
+			want: []Tag{{Key: "__name__", Value: "up"}, {Key: "job", Value: "a"}},
+		},
+		{
+			line: `msdos_file_access_time_seconds{path="C:\\DIR\\FILE.TXT",error="Cannot find file:\n\"FILE.TXT\""} 1.458255915e9`,
+			want: []Tag{
+				{Key: "__name__", Value: "msdos_file_access_time_seconds"},
+				{Key: "error", Value: "Cannot find file:\n\"FILE.TXT\""},
+				{Key: "path", Value: `C:\DIR\FILE.TXT`},
+			},
+		},
+		{line: `{job="a"}`, wantErr: ErrPathInvalid{"name", "not found"}},
+		{line: `up{1a="b"}`, wantErr: ErrPathInvalid{`1a="b"}`, "label name expected"}},
+		{line: `up{a:"b"}`, wantErr: ErrPathInvalid{`a:"b"}`, "not delimited with ="}},
+		{line: `up{a=b}`, wantErr: ErrPathInvalid{`a=b}`, "label value not quoted"}},
+		{line: `up{a="b}`, wantErr: ErrPathInvalid{`"b}`, "label value not terminated"}},
+		{line: `up{a="b\"}`, wantErr: ErrPathInvalid{`"b\"}`, "label value not terminated"}},
+		{line: `up{a="b\t"}`, wantErr: ErrPathInvalid{`"b\t"`, "invalid escape sequence"}},
+		{line: `up{a="b" c="d"}`, wantErr: ErrPathInvalid{`c="d"}`, "',' or '}' expected"}},
+		{line: `up{a="b"`, wantErr: ErrPathInvalid{`up{a="b"`, "'}' expected"}},
+		{line: `up{a="b"}1`, wantErr: ErrPathInvalid{"1", "unexpected symbols"}},
+		{line: `up{a="b",a="c"}`, wantErr: ErrPathInvalid{"a", "duplicate label"}},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.line, func(t *testing.T) {
+			tags, err := PrometheusTags(tt.line)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("PrometheusTags(%q) error = %v", tt.line, err)
+				}
+				if !cmp.Equal(tt.want, tags) {
+					t.Errorf("PrometheusTags(%q) = %s", tt.line, cmp.Diff(tt.want, tags))
+				}
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPrometheusTags_Match(t *testing.T) {
+	gtree := NewTree()
+	_, _, err := gtree.Add(`seriesByTag('name=http_requests_total', 'code=~^5', 'method!=get')`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		line string
+		want []int
+	}{
+		{line: `http_requests_total{method="post",code="503"} 3`, want: []int{0}},
+		{line: `http_requests_total{method="get",code="503"} 3`, want: nil},
+		{line: `http_requests_total{code="200"} 3`, want: nil},
+	} {
+		tags, err := PrometheusTags(tt.line)
+		if err != nil {
+			t.Fatalf("PrometheusTags(%q) error = %v", tt.line, err)
+		}
+		var store items.IndexStore
+		gtree.MatchByTags(tags, &store)
+		assert.Equal(t, tt.want, store.N, tt.line)
+	}
+}
+
+func TestPrometheusTagsB_Allocs(t *testing.T) {
+	line := `http_requests_total{method="post",code="200",instance="host:9090",job="api"} 1027 1395066363000`
+	tags := make([]Tag, 0, 8)
+	allocs := testing.AllocsPerRun(100, func() {
+		var err error
+		if tags, err = PrometheusTagsB(line, tags); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+	assert.Equal(t, 5, len(tags))
+}
+
+func TestSortTags(t *testing.T) {
+	tags := []Tag{{Key: "b"}, {Key: "__name__"}, {Key: "a"}, {Key: "c"}}
+	SortTags(tags)
+	assert.Equal(t, []Tag{{Key: "__name__"}, {Key: "a"}, {Key: "b"}, {Key: "c"}}, tags)
+}
+
+func BenchmarkPrometheusTagsB(b *testing.B) {
+	line := `http_requests_total{method="post",code="200",instance="host:9090",job="api"} 1027 1395066363000`
+	tags := make([]Tag, 0, 8)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if tags, err = PrometheusTagsB(line, tags); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
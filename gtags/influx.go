@@ -0,0 +1,205 @@
+package gtags
+
+import (
+	"strings"
+)
+
+// InfluxNameScheme define __name__ tag value for InfluxDB line protocol series
+type InfluxNameScheme int8
+
+const (
+	// InfluxNameMeasurement is a measurement as __name__ (one series per line)
+	InfluxNameMeasurement InfluxNameScheme = iota
+	// InfluxNameMeasurementField is a measurement and field key (joined with separator) as __name__ (one series per field)
+	InfluxNameMeasurementField
+)
+
+const (
+	influxMeasurementEscaped = ", "
+	influxKeyEscaped         = ",= "
+)
+
+// influxScan return position of first unescaped stop symbol (or end of string) and escaped flag
+func influxScan(s string, pos int, escapable, stops string) (end int, escaped bool) {
+	for end = pos; end < len(s); end++ {
+		c := s[end]
+		if c == '\\' && end+1 < len(s) && strings.IndexByte(escapable, s[end+1]) != -1 {
+			escaped = true
+			end++
+		} else if strings.IndexByte(stops, c) != -1 {
+			return
+		}
+	}
+	return
+}
+
+// influxUnescape unescape InfluxDB line protocol escape sequences for escapable symbols (other backslashes are literal)
+func influxUnescape(s string, escapable string) string {
+	var buf strings.Builder
+	buf.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(escapable, s[i+1]) != -1 {
+			i++
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String()
+}
+
+func influxToken(s string, pos int, escapable, stops string) (token string, end int) {
+	end, escaped := influxScan(s, pos, escapable, stops)
+	token = s[pos:end]
+	if escaped {
+		token = influxUnescape(token, escapable)
+	}
+	return
+}
+
+// influxSeriesKey parse measurement and tag set, return unsorted tags (measurement is a __name__) and fields position
+func influxSeriesKey(line string) (tags []Tag, pos int, err error) {
+	if line == "" || line[0] == '#' {
+		err = ErrPathInvalid{line, "measurement not found"}
+		return
+	}
+	var measurement string
+	if measurement, pos = influxToken(line, 0, influxMeasurementEscaped, influxMeasurementEscaped); measurement == "" {
+		err = ErrPathInvalid{line, "measurement not found"}
+		return
+	}
+	tags = make([]Tag, 1, strings.Count(line, "=")+1)
+	tags[0] = Tag{Key: "__name__", Value: measurement}
+
+	for pos < len(line) && line[pos] == ',' {
+		start := pos + 1
+		var key, value string
+		if key, pos = influxToken(line, start, influxKeyEscaped, influxKeyEscaped); key == "" {
+			err = ErrPathInvalid{line[start:], "tag key not found"}
+			return
+		}
+		if pos == len(line) || line[pos] != '=' {
+			err = ErrPathInvalid{line[start:], "not delimited with ="}
+			return
+		}
+		if value, pos = influxToken(line, pos+1, influxKeyEscaped, influxKeyEscaped); value == "" {
+			err = ErrPathInvalid{line[start:], "tag value not found"}
+			return
+		}
+		if pos < len(line) && line[pos] == '=' {
+			err = ErrPathInvalid{line[start:], "unescaped ="}
+			return
+		}
+		tags = append(tags, Tag{Key: key, Value: value})
+	}
+	if pos < len(line) {
+		// skip space before fields
+		pos++
+	}
+
+	return
+}
+
+// influxFields parse field keys from field set (values are skipped)
+func influxFields(line string, pos int) (fields []string, err error) {
+	for {
+		start := pos
+		var key string
+		if key, pos = influxToken(line, start, influxKeyEscaped, influxKeyEscaped); key == "" {
+			err = ErrPathInvalid{line[start:], "field key not found"}
+			return
+		}
+		if pos == len(line) || line[pos] != '=' {
+			err = ErrPathInvalid{line[start:], "not delimited with ="}
+			return
+		}
+		pos++
+		if pos < len(line) && line[pos] == '"' {
+			// string field value, with escaped \" and \\
+			for pos++; pos < len(line) && line[pos] != '"'; pos++ {
+				if line[pos] == '\\' {
+					pos++
+				}
+			}
+			if pos >= len(line) {
+				err = ErrPathInvalid{line[start:], "field value not terminated"}
+				return
+			}
+			pos++
+		} else {
+			valueStart := pos
+			for pos < len(line) && line[pos] != ',' && line[pos] != ' ' {
+				pos++
+			}
+			if pos == valueStart {
+				err = ErrPathInvalid{line[start:], "field value not found"}
+				return
+			}
+		}
+		fields = append(fields, key)
+		if pos == len(line) || line[pos] == ' ' {
+			// end of line or timestamp
+			return
+		}
+		if line[pos] != ',' {
+			err = ErrPathInvalid{line[pos:], "',' expected"}
+			return
+		}
+		pos++
+	}
+}
+
+func checkDupTags(tags []Tag) error {
+	for i := 1; i < len(tags); i++ {
+		if tags[i].Key == tags[i-1].Key {
+			return ErrPathInvalid{tags[i].Key, "duplicate tag"}
+		}
+	}
+	return nil
+}
+
+// InfluxTags split InfluxDB line protocol series (like cpu,host=a,region=b field=1 ts) into sorted Tag's slice,
+// measurement is a __name__ (field set is not required).
+func InfluxTags(line string) (tags []Tag, err error) {
+	if tags, _, err = influxSeriesKey(line); err != nil {
+		return
+	}
+	SortTags(tags)
+	err = checkDupTags(tags)
+	return
+}
+
+// InfluxSeries split InfluxDB line protocol line (like cpu,host=a,region=b usage=1,idle=2 ts) into sorted Tag's slices.
+// With InfluxNameMeasurement scheme return single series with measurement as __name__,
+// with InfluxNameMeasurementField return series per field with measurement + separator + field as __name__.
+func InfluxSeries(line string, scheme InfluxNameScheme, separator string) (series [][]Tag, err error) {
+	tags, pos, err := influxSeriesKey(line)
+	if err != nil {
+		return
+	}
+	if pos == len(line) {
+		err = ErrPathInvalid{line, "fields not found"}
+		return
+	}
+	var fields []string
+	if fields, err = influxFields(line, pos); err != nil {
+		return
+	}
+	SortTags(tags)
+	if err = checkDupTags(tags); err != nil {
+		return
+	}
+
+	if scheme == InfluxNameMeasurementField {
+		series = make([][]Tag, 0, len(fields))
+		measurement := tags[0].Value
+		for _, field := range fields {
+			fieldTags := make([]Tag, len(tags))
+			copy(fieldTags, tags)
+			fieldTags[0].Value = measurement + separator + field
+			series = append(series, fieldTags)
+		}
+	} else {
+		series = [][]Tag{tags}
+	}
+
+	return
+}
@@ -0,0 +1,128 @@
+package gtags
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/msaf1980/go-matcher/pkg/items"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfluxTags(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []Tag
+		wantErr error
+	}{
+		{line: "cpu", want: []Tag{{Key: "__name__", Value: "cpu"}}},
+		{line: "cpu usage=1", want: []Tag{{Key: "__name__", Value: "cpu"}}},
+		{
+			line: "cpu,region=b,host=a usage=1,idle=2 1465839830100400200",
+			want: []Tag{{Key: "__name__", Value: "cpu"}, {Key: "host", Value: "a"}, {Key: "region", Value: "b"}},
+		},
+		{
+			// escaped commas, spaces and equals
+			line: `my\ cpu\,x,host\=name=a\ b\,c,path=C:\dir value=1`,
+			want: []Tag{{Key: "__name__", Value: "my cpu,x"}, {Key: "host=name", Value: "a b,c"}, {Key: "path", Value: `C:\dir`}},
+		},
+		{
+			// equals sign in measurement is not escaped
+			line: `a\=b=c,t=v f=1`,
+			want: []Tag{{Key: "__name__", Value: `a\=b=c`}, {Key: "t", Value: "v"}},
+		},
+		{line: "", wantErr: ErrPathInvalid{"", "measurement not found"}},
+		{line: "# comment", wantErr: ErrPathInvalid{"# comment", "measurement not found"}},
+		{line: ",host=a f=1", wantErr: ErrPathInvalid{",host=a f=1", "measurement not found"}},
+		{line: "cpu,=a f=1", wantErr: ErrPathInvalid{"=a f=1", "tag key not found"}},
+		{line: "cpu,host f=1", wantErr: ErrPathInvalid{"host f=1", "not delimited with ="}},
+		{line: "cpu,host= f=1", wantErr: ErrPathInvalid{"host= f=1", "tag value not found"}},
+		{line: "cpu,host=a=b f=1", wantErr: ErrPathInvalid{"host=a=b f=1", "unescaped ="}},
+		{line: "cpu,host=a,host=b f=1", wantErr: ErrPathInvalid{"host", "duplicate tag"}},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.line, func(t *testing.T) {
+			tags, err := InfluxTags(tt.line)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("InfluxTags(%q) error = %v", tt.line, err)
+				}
+				if !cmp.Equal(tt.want, tags) {
+					t.Errorf("InfluxTags(%q) = %s", tt.line, cmp.Diff(tt.want, tags))
+				}
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestInfluxSeries(t *testing.T) {
+	tests := []struct {
+		line    string
+		scheme  InfluxNameScheme
+		want    [][]Tag
+		wantErr error
+	}{
+		{
+			line:   "cpu,host=a usage=1,idle=2i 1465839830100400200",
+			scheme: InfluxNameMeasurement,
+			want:   [][]Tag{{{Key: "__name__", Value: "cpu"}, {Key: "host", Value: "a"}}},
+		},
+		{
+			line:   `cpu,host=a usage=1,msg="a, b=\"c\" \\",idle=2i 1465839830100400200`,
+			scheme: InfluxNameMeasurementField,
+			want: [][]Tag{
+				{{Key: "__name__", Value: "cpu_usage"}, {Key: "host", Value: "a"}},
+				{{Key: "__name__", Value: "cpu_msg"}, {Key: "host", Value: "a"}},
+				{{Key: "__name__", Value: "cpu_idle"}, {Key: "host", Value: "a"}},
+			},
+		},
+		{
+			line:   `cpu usage\ user=1`,
+			scheme: InfluxNameMeasurementField,
+			want:   [][]Tag{{{Key: "__name__", Value: "cpu_usage user"}}},
+		},
+		{line: "cpu,host=a", scheme: InfluxNameMeasurementField, wantErr: ErrPathInvalid{"cpu,host=a", "fields not found"}},
+		{line: "cpu =1", scheme: InfluxNameMeasurementField, wantErr: ErrPathInvalid{"=1", "field key not found"}},
+		{line: "cpu usage", scheme: InfluxNameMeasurementField, wantErr: ErrPathInvalid{"usage", "not delimited with ="}},
+		{line: "cpu usage=", scheme: InfluxNameMeasurementField, wantErr: ErrPathInvalid{"usage=", "field value not found"}},
+		{line: `cpu msg="a`, scheme: InfluxNameMeasurementField, wantErr: ErrPathInvalid{`msg="a`, "field value not terminated"}},
+		{line: `cpu msg="a"b`, scheme: InfluxNameMeasurementField, wantErr: ErrPathInvalid{"b", "',' expected"}},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.line, func(t *testing.T) {
+			series, err := InfluxSeries(tt.line, tt.scheme, "_")
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("InfluxSeries(%q) error = %v", tt.line, err)
+				}
+				if !cmp.Equal(tt.want, series) {
+					t.Errorf("InfluxSeries(%q) = %s", tt.line, cmp.Diff(tt.want, series))
+				}
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestInfluxSeries_Match(t *testing.T) {
+	gtree := NewTree()
+	_, _, err := gtree.Add(`seriesByTag('name=~^cpu_(usage|idle)$', 'host=a*')`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	series, err := InfluxSeries("cpu,region=eu,host=a1 usage=1,user=2,idle=3", InfluxNameMeasurementField, "_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var matched []string
+	for _, tags := range series {
+		var store items.IndexStore
+		if gtree.MatchByTags(tags, &store) > 0 {
+			matched = append(matched, tags[0].Value)
+		}
+	}
+	assert.Equal(t, []string{"cpu_usage", "cpu_idle"}, matched)
+}
@@ -7,7 +7,8 @@ import (
 type TaggedItems struct {
 	Key        string
 	NotMatched []*TaggedItem
-	Matched    []*TaggedItem
+	Matched    []*TaggedItem          // wildcard and regexp match
+	MatchedMap map[string]*TaggedItem // full match (= without wildcards)
 }
 
 func (items *TaggedItems) Get(n int) *TaggedItem {
@@ -20,8 +21,8 @@ type TaggedItem struct {
 	// seriesByTag()
 	items.Terminated
 
-	// TODO: may be scan equal without wildcards with map ?
-	Items []TaggedItems // next possible parts tree (by key)
+	Items    []TaggedItems // next possible parts tree (by key)
+	KeyItems []*TaggedItem // next possible parts tree for terms with wildcard (regexp) key, checked against all tags
 }
 
 func hasName(items []TaggedItems) bool {
@@ -214,20 +215,46 @@ func find(tags []Tag, key string, start int) int {
 }
 
 func (item *TaggedItem) Parse(terms TaggedTermList, query string, index int) (lastItem *TaggedItem) {
+	pterms := make([]*TaggedTerm, len(terms))
+	for i := range terms {
+		pterms[i] = &terms[i]
+	}
+	return item.parse(pterms, query, index)
+}
+
+func (item *TaggedItem) parse(terms []*TaggedTerm, query string, index int) (lastItem *TaggedItem) {
 	var (
 		isMatchedOp bool
 		childs      []*TaggedItem
 	)
-	pos := item.findOrAppend(terms[0].Key)
-	switch terms[0].Op {
-	case TaggedTermEq, TaggedTermMatch:
+	term := terms[0]
+	if term.IsWildcardKey() {
+		return item.parseKey(terms, query, index)
+	}
+	pos := item.findOrAppend(term.Key)
+	if term.Op == TaggedTermEq && !term.HasWildcard {
+		// full match
+		if item.Items[pos].MatchedMap == nil {
+			item.Items[pos].MatchedMap = make(map[string]*TaggedItem)
+		}
+		if lastItem = item.Items[pos].MatchedMap[term.Value]; lastItem == nil {
+			lastItem = &TaggedItem{Term: term}
+			item.Items[pos].MatchedMap[term.Value] = lastItem
+		}
+		if len(terms) > 1 {
+			lastItem = lastItem.parse(terms[1:], query, index)
+		}
+		return
+	}
+	switch term.Op {
+	case TaggedTermEq, TaggedTermMatch, TaggedTermGt, TaggedTermGe, TaggedTermLt, TaggedTermLe:
 		isMatchedOp = true
 		childs = item.Items[pos].Matched
 	default:
 		childs = item.Items[pos].NotMatched
 	}
 	for _, child := range childs {
-		if terms[0].Key == child.Term.Key && terms[0].Op == child.Term.Op && terms[0].Value == child.Term.Value {
+		if term.Key == child.Term.Key && term.Op == child.Term.Op && term.Value == child.Term.Value && term.kind() == child.Term.kind() {
 			lastItem = child
 			break
 		}
@@ -235,8 +262,7 @@ func (item *TaggedItem) Parse(terms TaggedTermList, query string, index int) (la
 
 	if lastItem == nil {
 		// not found
-		// TODO: items caching ?
-		lastItem = &TaggedItem{Term: &terms[0]}
+		lastItem = &TaggedItem{Term: term}
 		childs = append(childs, lastItem)
 		if isMatchedOp {
 			item.Items[pos].Matched = childs
@@ -246,110 +272,223 @@ func (item *TaggedItem) Parse(terms TaggedTermList, query string, index int) (la
 	}
 
 	if len(terms) > 1 {
-		lastItem = lastItem.Parse(terms[1:], query, index)
+		lastItem = lastItem.parse(terms[1:], query, index)
 	}
 
 	return
 }
 
-func (item *TaggedItem) MatchByTagsMap(tags map[string]string, store items.Store) (matched int) {
-	if len(tags) == 0 {
-		return
+// parseKey parse term with wildcard (regexp) key into KeyItems
+func (item *TaggedItem) parseKey(terms []*TaggedTerm, query string, index int) (lastItem *TaggedItem) {
+	term := terms[0]
+	for _, child := range item.KeyItems {
+		if term.Key == child.Term.Key && term.Op == child.Term.Op && term.Value == child.Term.Value && term.kind() == child.Term.kind() {
+			lastItem = child
+			break
+		}
+	}
+	if lastItem == nil {
+		// not found
+		lastItem = &TaggedItem{Term: term}
+		item.KeyItems = append(item.KeyItems, lastItem)
 	}
+	if len(terms) > 1 {
+		lastItem = lastItem.parse(terms[1:], query, index)
+	}
+	return
+}
+
+const (
+	memoUnknown uint8 = iota
+	memoMatched
+	memoNotMatched
+)
+
+// termsMemo is a per match call interned terms results (by term id)
+type termsMemo struct {
+	results []uint8
+}
+
+func (m *termsMemo) reset(n int) {
+	if cap(m.results) < n {
+		m.results = make([]uint8, n)
+	} else {
+		m.results = m.results[:n]
+		for i := range m.results {
+			m.results[i] = memoUnknown
+		}
+	}
+}
+
+// cached check for memoized term result
+func (m *termsMemo) cached(term *TaggedTerm) bool {
+	return m != nil && term.id != 0 && term.id < len(m.results) && m.results[term.id] != memoUnknown
+}
+
+// store memoize term result
+func (m *termsMemo) store(term *TaggedTerm, matched bool) bool {
+	if m != nil && term.id != 0 && term.id < len(m.results) {
+		if matched {
+			m.results[term.id] = memoMatched
+		} else {
+			m.results[term.id] = memoNotMatched
+		}
+	}
+	return matched
+}
+
+// match evaluate term (regexp/glob and numeric results are memoized for interned terms)
+func (m *termsMemo) match(term *TaggedTerm, v string) bool {
+	if term.Re == nil && !term.HasWildcard && !term.Op.IsNumeric() {
+		return term.Match(v)
+	}
+	if m.cached(term) {
+		return m.results[term.id] == memoMatched
+	}
+	return m.store(term, term.Match(v))
+}
+
+// matchKeyTags evaluate wildcard key term against all tags (results are memoized for interned terms)
+func (m *termsMemo) matchKeyTags(term *TaggedTerm, tags []Tag) bool {
+	if m.cached(term) {
+		return m.results[term.id] == memoMatched
+	}
+	return m.store(term, term.MatchKeyTags(tags))
+}
 
+// matchKeySource evaluate wildcard key term against tags source (results are memoized for interned terms)
+func (m *termsMemo) matchKeySource(term *TaggedTerm, src TagSource) bool {
+	if m.cached(term) {
+		return m.results[term.id] == memoMatched
+	}
+	return m.store(term, term.MatchKeySource(src))
+}
+
+func (item *TaggedItem) MatchByTagsMap(tags map[string]string, store items.Store) (matched int) {
+	return item.matchByTagsMap(tags, store, nil)
+}
+
+func (item *TaggedItem) matchByTagsMap(tags map[string]string, store items.Store, memo *termsMemo) (matched int) {
+	return item.matchBySource(TagMapSource(tags), store, memo)
+}
+
+func (item *TaggedItem) MatchBySource(src TagSource, store items.Store) (matched int) {
+	return item.matchBySource(src, store, nil)
+}
+
+func (item *TaggedItem) matchBySource(src TagSource, store items.Store, memo *termsMemo) (matched int) {
 	for i := 0; i < len(item.Items); i++ {
-		v, ok := tags[item.Items[i].Key]
-		if ok {
-			for _, child := range item.Items[i].Matched {
-				if !child.Term.Match(v) {
-					continue
-				}
-				if child.Terminate {
-					store.Store(child.Query, child.Index)
-					matched++
-				}
-				if n := child.MatchByTagsMap(tags, store); n > 0 {
-					matched += n
-				}
+		// absent tag is an empty value (graphite semantics)
+		v, _ := src.Get(item.Items[i].Key)
+		if child, ok := item.Items[i].MatchedMap[v]; ok {
+			if child.Terminate {
+				store.Store(child.Query, child.Index)
+				matched++
 			}
-			for _, child := range item.Items[i].NotMatched {
-				if !child.Term.Match(v) {
-					continue
-				}
-				if child.Terminate {
-					store.Store(child.Query, child.Index)
-					matched++
-				}
-				if n := child.MatchByTagsMap(tags, store); n > 0 {
-					matched += n
-				}
+			if n := child.matchBySource(src, store, memo); n > 0 {
+				matched += n
 			}
-		} else {
-			// tags not exist, check not matched
-			for _, child := range item.Items[i].NotMatched {
-				if child.Terminate {
-					store.Store(child.Query, child.Index)
-					matched++
-				}
-				if n := child.MatchByTagsMap(tags, store); n > 0 {
-					matched += n
-				}
+		}
+		for _, child := range item.Items[i].Matched {
+			if !memo.match(child.Term, v) {
+				continue
+			}
+			if child.Terminate {
+				store.Store(child.Query, child.Index)
+				matched++
+			}
+			if n := child.matchBySource(src, store, memo); n > 0 {
+				matched += n
 			}
 		}
-
+		for _, child := range item.Items[i].NotMatched {
+			if !memo.match(child.Term, v) {
+				continue
+			}
+			if child.Terminate {
+				store.Store(child.Query, child.Index)
+				matched++
+			}
+			if n := child.matchBySource(src, store, memo); n > 0 {
+				matched += n
+			}
+		}
+	}
+	for _, child := range item.KeyItems {
+		if !memo.matchKeySource(child.Term, src) {
+			continue
+		}
+		if child.Terminate {
+			store.Store(child.Query, child.Index)
+			matched++
+		}
+		if n := child.matchBySource(src, store, memo); n > 0 {
+			matched += n
+		}
 	}
 
 	return
 }
 
 func (item *TaggedItem) MatchByTags(tags []Tag, store items.Store) (matched int) {
-	if len(tags) == 0 {
-		return
-	}
+	return item.matchByTags(tags, store, nil)
+}
 
+func (item *TaggedItem) matchByTags(tags []Tag, store items.Store, memo *termsMemo) (matched int) {
 	matchPos := 0
 
 	for i := 0; i < len(item.Items); i++ {
-		n := find(tags, item.Items[i].Key, matchPos)
-		if n == -1 {
-			// tags not exist, check not matched
-			for _, child := range item.Items[i].NotMatched {
-				if child.Terminate {
-					store.Store(child.Query, child.Index)
-					matched++
-				}
-				if n := child.MatchByTags(tags, store); n > 0 {
-					matched += n
-				}
-			}
-		} else {
+		// absent tag is an empty value (graphite semantics)
+		var v string
+		if n := find(tags, item.Items[i].Key, matchPos); n != -1 {
 			matchPos = n
-			for _, child := range item.Items[i].Matched {
-				if !child.Term.Match(tags[n].Value) {
-					continue
-				}
-				if child.Terminate {
-					store.Store(child.Query, child.Index)
-					matched++
-				}
-				if n := child.MatchByTags(tags, store); n > 0 {
-					matched += n
-				}
+			v = tags[n].Value
+		}
+		if child, ok := item.Items[i].MatchedMap[v]; ok {
+			if child.Terminate {
+				store.Store(child.Query, child.Index)
+				matched++
 			}
-			for _, child := range item.Items[i].NotMatched {
-				if !child.Term.Match(tags[n].Value) {
-					continue
-				}
-				if child.Terminate {
-					store.Store(child.Query, child.Index)
-					matched++
-				}
-				if n := child.MatchByTags(tags, store); n > 0 {
-					matched += n
-				}
+			if n := child.matchByTags(tags, store, memo); n > 0 {
+				matched += n
 			}
 		}
-
+		for _, child := range item.Items[i].Matched {
+			if !memo.match(child.Term, v) {
+				continue
+			}
+			if child.Terminate {
+				store.Store(child.Query, child.Index)
+				matched++
+			}
+			if n := child.matchByTags(tags, store, memo); n > 0 {
+				matched += n
+			}
+		}
+		for _, child := range item.Items[i].NotMatched {
+			if !memo.match(child.Term, v) {
+				continue
+			}
+			if child.Terminate {
+				store.Store(child.Query, child.Index)
+				matched++
+			}
+			if n := child.matchByTags(tags, store, memo); n > 0 {
+				matched += n
+			}
+		}
+	}
+	for _, child := range item.KeyItems {
+		if !memo.matchKeyTags(child.Term, tags) {
+			continue
+		}
+		if child.Terminate {
+			store.Store(child.Query, child.Index)
+			matched++
+		}
+		if n := child.matchByTags(tags, store, memo); n > 0 {
+			matched += n
+		}
 	}
 
 	return
@@ -2,11 +2,13 @@ package gtags
 
 import (
 	"reflect"
+	"sort"
 	"strconv"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/msaf1980/go-matcher/pkg/items"
+	"github.com/stretchr/testify/assert"
 )
 
 type testFindItems struct {
@@ -281,3 +283,52 @@ func TestFindTaggedItem(t *testing.T) {
 		}
 	}
 }
+
+func TestTaggedItemParse_MatchedMap(t *testing.T) {
+	gtree := NewTree()
+	queries := []string{
+		"seriesByTag('name=a', 'b=c')",
+		"seriesByTag('name=a', 'b=d')",
+		"seriesByTag('name=a', 'b=c*')",
+		"seriesByTag('name=a', 'b=~^c')",
+		"seriesByTag('name=a', 'b!=c')",
+	}
+	for i, query := range queries {
+		if _, _, err := gtree.Add(query, i); err != nil {
+			t.Fatalf("GTagsTree.Add(%q) error = %v", query, err)
+		}
+	}
+
+	name := gtree.Root.Items[0].MatchedMap["a"]
+	if name == nil {
+		t.Fatalf("__name__=a not in full match map")
+	}
+	assert.Equal(t, 0, len(gtree.Root.Items[0].Matched))
+	assert.Equal(t, 1, len(name.Items))
+	assert.Equal(t, "b", name.Items[0].Key)
+	assert.Equal(t, []string{"b=c", "b=d"}, []string{name.Items[0].MatchedMap["c"].Term.String(), name.Items[0].MatchedMap["d"].Term.String()})
+	assert.Equal(t, 2, len(name.Items[0].Matched))
+	assert.Equal(t, 1, len(name.Items[0].NotMatched))
+
+	for path, want := range map[string][]int{
+		"a?b=c":  {0, 2, 3},
+		"a?b=d":  {1, 4},
+		"a?b=cd": {2, 3, 4},
+		"a?e=f":  {4},
+		"b?b=c":  nil,
+	} {
+		tags, err := PathTags(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var store items.IndexStore
+		gtree.MatchByTags(tags, &store)
+		sort.Ints(store.N)
+		assert.Equal(t, want, store.N, path)
+
+		store.N = nil
+		gtree.MatchByTagsMap(TagsMap(tags), &store)
+		sort.Ints(store.N)
+		assert.Equal(t, want, store.N, path)
+	}
+}
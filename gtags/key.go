@@ -0,0 +1,109 @@
+package gtags
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/msaf1980/go-matcher/glob"
+	"github.com/msaf1980/go-matcher/pkg/items"
+)
+
+// buildKey compile key glob (for key with wildcards, like k8s_*) or key regexp (for key like ~^debug)
+func (term *TaggedTerm) buildKey(dialect Dialect) (err error) {
+	if strings.HasPrefix(term.Key, "~") {
+		re := dialect.anchorRegexp(term.Key[1:])
+		if re == "" {
+			return ErrExprInvalid{term.Key}
+		}
+		if term.KeyRe, err = regexp.Compile(re); err != nil {
+			return ErrExprInvalid{term.Key}
+		}
+		term.Key = "~" + re
+	} else if items.HasWildcard(term.Key) {
+		if term.KeyGlob, err = glob.Parse(term.Key); err != nil {
+			return
+		}
+		term.Key = term.KeyGlob.Node
+		if len(term.KeyGlob.Items) == 0 {
+			term.KeyGlob = nil
+		}
+	}
+	return
+}
+
+// IsWildcardKey check for term with wildcard or regexp key
+func (term *TaggedTerm) IsWildcardKey() bool {
+	return term.KeyGlob != nil || term.KeyRe != nil
+}
+
+// MatchKey check tag key (for literal key compare with Key)
+func (term *TaggedTerm) MatchKey(key string) bool {
+	if term.KeyGlob != nil {
+		return term.KeyGlob.Match(key)
+	}
+	if term.KeyRe != nil {
+		return term.KeyRe.MatchString(key)
+	}
+	return key == term.Key
+}
+
+// negative check for negative term (!= or !=~)
+func (term *TaggedTerm) negative() bool {
+	return term.Op == TaggedTermNe || term.Op == TaggedTermNotMatch
+}
+
+// matchKeyValue check value of tag with matched key, return true if result is determined
+// (any key matches for positive term or some key not matches for negative term)
+func (term *TaggedTerm) matchKeyValue(v string) (result, done bool) {
+	if term.Match(v) != term.negative() {
+		return !term.negative(), true
+	}
+	return false, false
+}
+
+// MatchKeyTags check wildcard key term against all tags. Positive term (=, =~ or numeric) is matched, if any tag with matched key
+// has matched value, negative term (!= or !=~) is matched, if no tag with matched key has value, matched without negation.
+// Tags with empty value are absent (graphite semantics), so without matched keys positive term is not matched and negative term is matched.
+func (term *TaggedTerm) MatchKeyTags(tags []Tag) bool {
+	for i := range tags {
+		if tags[i].Value == "" || !term.MatchKey(tags[i].Key) {
+			continue
+		}
+		if result, done := term.matchKeyValue(tags[i].Value); done {
+			return result
+		}
+	}
+	return term.negative()
+}
+
+// MatchKeySource check wildcard key term against tags source (like MatchKeyTags).
+// Source must implement SortedTagSource or RangeTagSource, for other sources no keys are matched.
+func (term *TaggedTerm) MatchKeySource(src TagSource) (result bool) {
+	switch src := src.(type) {
+	case SortedTagSource:
+		return term.MatchKeyTags(src.SortedTags())
+	case TagMapSource:
+		for k, v := range src {
+			if v == "" || !term.MatchKey(k) {
+				continue
+			}
+			if result, done := term.matchKeyValue(v); done {
+				return result
+			}
+		}
+	case RangeTagSource:
+		result = term.negative()
+		src.Range(func(k, v string) bool {
+			if v == "" || !term.MatchKey(k) {
+				return true
+			}
+			r, done := term.matchKeyValue(v)
+			if done {
+				result = r
+			}
+			return !done
+		})
+		return
+	}
+	return term.negative()
+}
@@ -0,0 +1,80 @@
+package gtags
+
+import "sort"
+
+// TermsOrder is a terms order on GTagsTree build (normalized queries are not changed, terms are sorted by key)
+type TermsOrder int8
+
+const (
+	// OrderByKey build tree with terms, sorted by key (__name__ is first)
+	OrderByKey TermsOrder = iota
+	// OrderBySelectivity build tree with terms, ordered by estimated cost and selectivity:
+	// literal = first, then numeric, globs, regexps, wildcard keys and negative terms
+	OrderBySelectivity
+)
+
+// cost return estimated term cost (less is cheaper and more selective)
+func (term *TaggedTerm) cost() int {
+	if term.IsWildcardKey() {
+		// check all tags
+		if term.negative() {
+			return 7
+		}
+		return 4
+	}
+	switch term.Op {
+	case TaggedTermEq:
+		if term.HasWildcard {
+			return 2
+		}
+		if term.Value == "" {
+			// match absent tag, not selective
+			return 5
+		}
+		return 0
+	case TaggedTermMatch:
+		return 3
+	case TaggedTermNe:
+		if term.HasWildcard {
+			return 6
+		}
+		return 5
+	case TaggedTermNotMatch:
+		return 6
+	default:
+		// numeric
+		return 1
+	}
+}
+
+// termLess compare terms by key (__name__ is first), op and value
+func termLess(a, b *TaggedTerm) bool {
+	if a.Key == b.Key {
+		if a.Op == b.Op {
+			return a.Value < b.Value
+		}
+		return a.Op < b.Op
+	}
+	if a.Key == "__name__" {
+		return true
+	} else if b.Key == "__name__" {
+		return false
+	}
+	return a.Key < b.Key
+}
+
+// orderTerms reorder terms (sorted by key) for tree build
+func orderTerms(terms []*TaggedTerm, order TermsOrder) {
+	if order == OrderBySelectivity {
+		sort.SliceStable(terms, func(i, j int) bool {
+			return terms[i].cost() < terms[j].cost()
+		})
+	}
+}
+
+// sortTermMatches sort terms match details in normalized query order
+func sortTermMatches(details []TermMatch) {
+	sort.Slice(details, func(i, j int) bool {
+		return termLess(details[i].Term, details[j].Term)
+	})
+}
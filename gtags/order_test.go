@@ -0,0 +1,167 @@
+package gtags
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/msaf1980/go-matcher/pkg/items"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaggedTerm_Cost(t *testing.T) {
+	terms, err := ParseSeriesByTag("seriesByTag('a=x', 'b=', 'c=x*', 'd=~x', 'e!=x', 'f!=~x', 'g>1', 'k*=x', 'l*!=x')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pterms := make([]*TaggedTerm, len(terms))
+	for i := range terms {
+		pterms[i] = &terms[i]
+	}
+	orderTerms(pterms, OrderBySelectivity)
+	got := make([]string, len(pterms))
+	for i, term := range pterms {
+		got[i] = term.String()
+	}
+	assert.Equal(t, []string{"a=x", "g>1", "c=x*", "d=~x", "k*=x", "b=", "e!=x", "f!=~x", "l*!=x"}, got)
+	// normalized query is not changed
+	assert.Equal(t, "seriesByTag('a=x','b=','c=x*','d=~x','e!=x','f!=~x','g>1','k*=x','l*!=x')", terms.String())
+}
+
+func TestGTagsTree_OrderBySelectivity(t *testing.T) {
+	queries := []string{
+		"seriesByTag('name=~^cpu', 'dc=a', 'host!=h1', 'env=p*')",
+		"seriesByTag('name=~^cpu', 'dc=a')",
+		"seriesByTag('name=mem', 'dc=~^(a|b)$')",
+	}
+	gtree := NewTree()
+	gtree.Order = OrderBySelectivity
+	byKey := NewTree()
+	for i, query := range queries {
+		if _, _, err := gtree.Add(query, i); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := byKey.Add(query, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := &taggedItemStr{
+		Items: []taggedItemsStr{
+			{
+				Key: "__name__",
+				Matched: []*taggedItemStr{
+					{
+						Term: "__name__=mem",
+						Items: []taggedItemsStr{
+							{
+								Key: "dc",
+								Matched: []*taggedItemStr{
+									{
+										Term: "dc=~^(a|b)$",
+										Terminated: items.Terminated{
+											Terminate: true, Query: "seriesByTag('__name__=mem','dc=~^(a|b)$')", Index: 2,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			{
+				Key: "dc",
+				Matched: []*taggedItemStr{
+					{
+						Term: "dc=a",
+						Items: []taggedItemsStr{
+							{
+								Key: "__name__",
+								Matched: []*taggedItemStr{
+									{
+										Term: "__name__=~^cpu",
+										Terminated: items.Terminated{
+											Terminate: true, Query: "seriesByTag('__name__=~^cpu','dc=a')", Index: 1,
+										},
+									},
+								},
+							},
+							{
+								Key: "env",
+								Matched: []*taggedItemStr{
+									{
+										Term: "env=p*",
+										Items: []taggedItemsStr{
+											{
+												Key: "__name__",
+												Matched: []*taggedItemStr{
+													{
+														Term: "__name__=~^cpu",
+														Items: []taggedItemsStr{
+															{
+																Key: "host",
+																NotMatched: []*taggedItemStr{
+																	{
+																		Term: "host!=h1",
+																		Terminated: items.Terminated{
+																			Terminate: true,
+																			Query:     "seriesByTag('__name__=~^cpu','dc=a','env=p*','host!=h1')",
+																		},
+																	},
+																},
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if got := StringTaggedItem(gtree.Root); !reflect.DeepEqual(want, got) {
+		t.Fatalf("GTagsTree.Root = %s", cmp.Diff(want, got))
+	}
+	// normalized queries are not changed
+	assert.Equal(t, byKey.Queries, gtree.Queries)
+	assert.Equal(t, byKey.QueryIndex, gtree.QueryIndex)
+
+	for path, want := range map[string][]int{
+		"cpu?dc=a&env=prod&host=h2":      {0, 1},
+		"cpu_user?dc=a&env=prod&host=h1": {1},
+		"cpu?dc=b&env=prod":              nil,
+		"mem?dc=b&env=prod":              {2},
+	} {
+		tags, err := PathTags(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got, wantStore items.IndexStore
+		gtree.MatchByTags(tags, &got)
+		byKey.MatchByTags(tags, &wantStore)
+		sort.Ints(got.N)
+		sort.Ints(wantStore.N)
+		assert.Equal(t, want, got.N, path)
+		assert.Equal(t, wantStore.N, got.N, path)
+
+		got.N = nil
+		gtree.MatchByTagsMap(TagsMap(tags), &got)
+		sort.Ints(got.N)
+		assert.Equal(t, want, got.N, path)
+
+		// details are in normalized query order
+		for _, m := range gtree.MatchDetailsByTags(tags, nil) {
+			terms, err := ParseSeriesByTag(m.Query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			details, _ := terms.MatchDetailsByTags(tags)
+			assert.Equal(t, stringTermMatches(details), stringTermMatches(m.Terms), m.Query)
+		}
+	}
+}
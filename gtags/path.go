@@ -0,0 +1,97 @@
+package gtags
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/msaf1980/go-matcher/pkg/escape"
+)
+
+// nameEscaper escape symbols, not escaped by escape.Path, but unescaped by escape.Unescape or splitted by PathTags
+var nameEscaper = strings.NewReplacer("+", "%2B", "=", "%3D")
+
+// ValidateTag check tag with graphite rules (tag name must be non-empty and not contain ;!^=,
+// tag value must be non-empty, not contain ; and not started with ~)
+func ValidateTag(tag Tag) error {
+	if tag.Key == "" {
+		return ErrTagInvalid{tag.Key, tag.Value, "empty name"}
+	}
+	if tag.Key != "__name__" && strings.ContainsAny(tag.Key, ";!^=") {
+		return ErrTagInvalid{tag.Key, tag.Value, "name contains forbidden symbols"}
+	}
+	if tag.Value == "" {
+		return ErrTagInvalid{tag.Key, tag.Value, "empty value"}
+	}
+	if strings.IndexByte(tag.Value, ';') != -1 {
+		return ErrTagInvalid{tag.Key, tag.Value, "value contains forbidden symbols"}
+	}
+	if tag.Value[0] == '~' {
+		return ErrTagInvalid{tag.Key, tag.Value, "value started with ~"}
+	}
+	return nil
+}
+
+// sortedTags validate tags and return tags, sorted by graphite rules (copy if tags are not sorted)
+func sortedTags(tags []Tag) ([]Tag, error) {
+	sorted := true
+	for i := 0; i < len(tags); i++ {
+		if err := ValidateTag(tags[i]); err != nil {
+			return nil, err
+		}
+		if i > 0 && !tagLess(&tags[i-1], &tags[i]) {
+			sorted = false
+		}
+	}
+	if !sorted {
+		tags = append(make([]Tag, 0, len(tags)), tags...)
+		SortTags(tags)
+	}
+	if len(tags) == 0 || tags[0].Key != "__name__" {
+		return nil, ErrPathInvalid{"name", "not found"}
+	}
+	if err := checkDupTags(tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func writePath(tags []Tag, sep, delim byte) (string, error) {
+	tags, err := sortedTags(tags)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	size := 0
+	for i := range tags {
+		size += len(tags[i].Key) + len(tags[i].Value) + 2
+	}
+	buf.Grow(size)
+
+	if _, err = nameEscaper.WriteString(&buf, escape.Path(tags[0].Value)); err != nil {
+		return "", err
+	}
+	for i := 1; i < len(tags); i++ {
+		if i == 1 {
+			buf.WriteByte(sep)
+		} else {
+			buf.WriteByte(delim)
+		}
+		escape.QueryTo(tags[i].Key, &buf)
+		buf.WriteByte('=')
+		escape.QueryTo(tags[i].Value, &buf)
+	}
+
+	return buf.String(), nil
+}
+
+// GraphitePath build graphite tagged path (like name;a=v1;b=v2) from tags (tags are sorted by key, name is first).
+// Tags are validated with ValidateTag, name is escaped with escape.Path and tags with escape.Query.
+func GraphitePath(tags []Tag) (string, error) {
+	return writePath(tags, ';', ';')
+}
+
+// MergeTreePath build GraphiteMergeTree tagged path (like name?a=v1&b=v2) from tags (tags are sorted by key, name is first).
+// Tags are validated with ValidateTag, name is escaped with escape.Path and tags with escape.Query.
+func MergeTreePath(tags []Tag) (string, error) {
+	return writePath(tags, '?', '&')
+}
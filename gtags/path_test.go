@@ -0,0 +1,104 @@
+package gtags
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphitePath(t *testing.T) {
+	tests := []struct {
+		tags          []Tag
+		wantGraphite  string
+		wantMergeTree string
+		wantErr       error
+	}{
+		{
+			tags:          []Tag{{Key: "__name__", Value: "cpu.load"}, {Key: "dc", Value: "x"}, {Key: "host", Value: "h1"}},
+			wantGraphite:  "cpu.load;dc=x;host=h1",
+			wantMergeTree: "cpu.load?dc=x&host=h1",
+		},
+		{
+			// unsorted
+			tags:          []Tag{{Key: "host", Value: "h1"}, {Key: "dc", Value: "x"}, {Key: "__name__", Value: "cpu"}},
+			wantGraphite:  "cpu;dc=x;host=h1",
+			wantMergeTree: "cpu?dc=x&host=h1",
+		},
+		{
+			// escaped
+			tags: []Tag{
+				{Key: "__name__", Value: "cpu load+1=a&b?"}, {Key: "a b", Value: "c&d=e+f"}, {Key: "path", Value: "/a/b%"},
+				{Key: "url", Value: "http://a?b=c~"},
+			},
+			wantGraphite:  "cpu%20load%2B1%3Da&b%3F;a+b=c%26d%3De%2Bf;path=%2Fa%2Fb%25;url=http%3A%2F%2Fa%3Fb%3Dc~",
+			wantMergeTree: "cpu%20load%2B1%3Da&b%3F?a+b=c%26d%3De%2Bf&path=%2Fa%2Fb%25&url=http%3A%2F%2Fa%3Fb%3Dc~",
+		},
+		{tags: []Tag{}, wantErr: ErrPathInvalid{"name", "not found"}},
+		{tags: []Tag{{Key: "dc", Value: "x"}}, wantErr: ErrPathInvalid{"name", "not found"}},
+		{tags: []Tag{{Key: "__name__", Value: "cpu"}, {Key: "", Value: "x"}}, wantErr: ErrTagInvalid{"", "x", "empty name"}},
+		{tags: []Tag{{Key: "__name__", Value: "cpu"}, {Key: "a!", Value: "x"}}, wantErr: ErrTagInvalid{"a!", "x", "name contains forbidden symbols"}},
+		{tags: []Tag{{Key: "__name__", Value: "cpu"}, {Key: "a^b", Value: "x"}}, wantErr: ErrTagInvalid{"a^b", "x", "name contains forbidden symbols"}},
+		{tags: []Tag{{Key: "__name__", Value: "cpu"}, {Key: "a=b", Value: "x"}}, wantErr: ErrTagInvalid{"a=b", "x", "name contains forbidden symbols"}},
+		{tags: []Tag{{Key: "__name__", Value: "cpu"}, {Key: "a;b", Value: "x"}}, wantErr: ErrTagInvalid{"a;b", "x", "name contains forbidden symbols"}},
+		{tags: []Tag{{Key: "__name__", Value: "cpu"}, {Key: "a", Value: ""}}, wantErr: ErrTagInvalid{"a", "", "empty value"}},
+		{tags: []Tag{{Key: "__name__", Value: "cpu"}, {Key: "a", Value: "x;y"}}, wantErr: ErrTagInvalid{"a", "x;y", "value contains forbidden symbols"}},
+		{tags: []Tag{{Key: "__name__", Value: "cpu"}, {Key: "a", Value: "~x"}}, wantErr: ErrTagInvalid{"a", "~x", "value started with ~"}},
+		{tags: []Tag{{Key: "__name__", Value: "cpu;x"}, {Key: "a", Value: "x"}}, wantErr: ErrTagInvalid{"__name__", "cpu;x", "value contains forbidden symbols"}},
+		{tags: []Tag{{Key: "__name__", Value: "cpu"}, {Key: "a", Value: "x"}, {Key: "a", Value: "y"}}, wantErr: ErrPathInvalid{"a", "duplicate tag"}},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.wantGraphite, func(t *testing.T) {
+			graphitePath, err := GraphitePath(tt.tags)
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				_, err = MergeTreePath(tt.tags)
+				assert.Equal(t, tt.wantErr, err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("GraphitePath() error = %v", err)
+			}
+			assert.Equal(t, tt.wantGraphite, graphitePath)
+			mergeTreePath, err := MergeTreePath(tt.tags)
+			if err != nil {
+				t.Fatalf("MergeTreePath() error = %v", err)
+			}
+			assert.Equal(t, tt.wantMergeTree, mergeTreePath)
+
+			// round-trip
+			want := append([]Tag{}, tt.tags...)
+			SortTags(want)
+
+			tags, err := GraphitePathTags(graphitePath)
+			if err != nil {
+				t.Fatalf("GraphitePathTags(%q) error = %v", graphitePath, err)
+			}
+			if !cmp.Equal(want, tags) {
+				t.Errorf("GraphitePathTags(%q) = %s", graphitePath, cmp.Diff(want, tags))
+			}
+			if graphitePath2, _ := GraphitePath(tags); graphitePath2 != graphitePath {
+				t.Errorf("GraphitePath(GraphitePathTags(%q)) = %q", graphitePath, graphitePath2)
+			}
+
+			tags, err = PathTags(mergeTreePath)
+			if err != nil {
+				t.Fatalf("PathTags(%q) error = %v", mergeTreePath, err)
+			}
+			if !cmp.Equal(want, tags) {
+				t.Errorf("PathTags(%q) = %s", mergeTreePath, cmp.Diff(want, tags))
+			}
+			if mergeTreePath2, _ := MergeTreePath(tags); mergeTreePath2 != mergeTreePath {
+				t.Errorf("MergeTreePath(PathTags(%q)) = %q", mergeTreePath, mergeTreePath2)
+			}
+		})
+	}
+}
+
+func TestGraphitePath_NotModified(t *testing.T) {
+	tags := []Tag{{Key: "host", Value: "h1"}, {Key: "__name__", Value: "cpu"}}
+	_, err := GraphitePath(tags)
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{{Key: "host", Value: "h1"}, {Key: "__name__", Value: "cpu"}}, tags)
+}
@@ -0,0 +1,275 @@
+package gtags
+
+import (
+	"regexp/syntax"
+	"strings"
+	"sync/atomic"
+
+	"github.com/msaf1980/go-matcher/gglob"
+	"github.com/msaf1980/go-matcher/glob"
+)
+
+// Prefilter is a cheap literals check for regexp and glob terms, runned before regexp (glob) engine
+type Prefilter struct {
+	// counters, must be first for 64-bit atomic alignment
+	checked  uint64
+	rejected uint64
+
+	Prefix   string   // required prefix
+	Suffix   string   // required suffix
+	Contains []string // required substrings
+	MinLen   int      // min value bytes len
+	MaxLen   int      // max value bytes len, -1 for unlimited
+	TrimDot  bool     // trim single trailing dot before check (dot-separated glob match path with trailing dot)
+
+	Count bool // count checked and rejected values (counters are shared, so it slow down parallel match)
+}
+
+func (p *Prefilter) empty() bool {
+	return p.Prefix == "" && p.Suffix == "" && len(p.Contains) == 0 && p.MinLen == 0 && p.MaxLen == -1
+}
+
+func (p *Prefilter) pass(v string) bool {
+	if p.TrimDot && v != "" && v[len(v)-1] == '.' {
+		v = v[:len(v)-1]
+	}
+	if len(v) < p.MinLen || (p.MaxLen != -1 && len(v) > p.MaxLen) {
+		return false
+	}
+	if !strings.HasPrefix(v, p.Prefix) || !strings.HasSuffix(v, p.Suffix) {
+		return false
+	}
+	for _, s := range p.Contains {
+		if !strings.Contains(v, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// Pass check value with prefilter (false if value can't be matched by regexp/glob)
+func (p *Prefilter) Pass(v string) bool {
+	if !p.Count {
+		return p.pass(v)
+	}
+	atomic.AddUint64(&p.checked, 1)
+	if p.pass(v) {
+		return true
+	}
+	atomic.AddUint64(&p.rejected, 1)
+	return false
+}
+
+// Stats return checked and rejected values count (if counting is enabled)
+func (p *Prefilter) Stats() (checked, rejected uint64) {
+	return atomic.LoadUint64(&p.checked), atomic.LoadUint64(&p.rejected)
+}
+
+// globPrefilter return prefilter for glob (nil if nothing to check)
+func globPrefilter(g *glob.Glob) *Prefilter {
+	p := &Prefilter{Prefix: g.Prefix, Suffix: g.Suffix, MinLen: g.MinLen, MaxLen: g.MaxLen}
+	if p.MaxLen < 0 {
+		p.MaxLen = -1
+	}
+	if p.empty() {
+		return nil
+	}
+	return p
+}
+
+// gglobPrefilter return prefilter for dot-separated glob (nil if nothing to check)
+func gglobPrefilter(gg *gglob.GGlob) *Prefilter {
+	if len(gg.Parts) == 0 {
+		return nil
+	}
+	p := &Prefilter{MinLen: gg.MinLen + len(gg.Parts) - 1, MaxLen: -1, TrimDot: true}
+
+	// literal parts before first wildcard part and after last
+	var buf strings.Builder
+	first := 0
+	for ; first < len(gg.Parts) && len(gg.Parts[first].Items) == 0; first++ {
+		buf.WriteString(gg.Parts[first].Node)
+		buf.WriteByte('.')
+	}
+	if first == len(gg.Parts) {
+		// without wildcards
+		return nil
+	}
+	buf.WriteString(gg.Parts[first].Prefix)
+	p.Prefix = buf.String()
+
+	last := len(gg.Parts) - 1
+	suffix := ""
+	for ; last > first && len(gg.Parts[last].Items) == 0; last-- {
+		suffix = "." + gg.Parts[last].Node + suffix
+	}
+	p.Suffix = gg.Parts[last].Suffix + suffix
+
+	if p.empty() {
+		return nil
+	}
+	return p
+}
+
+// regexpPrefilter return prefilter with required literals for regexp (nil if nothing to check)
+func regexpPrefilter(expr string) *Prefilter {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	re = re.Simplify()
+
+	p := &Prefilter{MaxLen: -1}
+	var literals []string
+	p.MinLen, literals = regexpLiterals(re)
+	if re.Op == syntax.OpConcat && len(re.Sub) > 1 {
+		if re.Sub[0].Op == syntax.OpBeginText {
+			p.Prefix = literalRun(re.Sub[1:])
+		}
+		if re.Sub[len(re.Sub)-1].Op == syntax.OpEndText {
+			p.Suffix = literalRunReverse(re.Sub[:len(re.Sub)-1])
+		}
+	}
+	for _, s := range literals {
+		if strings.Contains(p.Prefix, s) || strings.Contains(p.Suffix, s) {
+			continue
+		}
+		dup := false
+		for _, c := range p.Contains {
+			if c == s {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			p.Contains = append(p.Contains, s)
+		}
+	}
+
+	if p.empty() {
+		return nil
+	}
+	return p
+}
+
+func isLiteral(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpLiteral && re.Flags&syntax.FoldCase == 0
+}
+
+// literalRun return concatenated leading literals
+func literalRun(subs []*syntax.Regexp) string {
+	var buf strings.Builder
+	for _, sub := range subs {
+		if !isLiteral(sub) {
+			break
+		}
+		buf.WriteString(string(sub.Rune))
+	}
+	return buf.String()
+}
+
+// literalRunReverse return concatenated trailing literals
+func literalRunReverse(subs []*syntax.Regexp) string {
+	i := len(subs)
+	for i > 0 && isLiteral(subs[i-1]) {
+		i--
+	}
+	return literalRun(subs[i:])
+}
+
+// regexpLiterals return min bytes len of matched string and required literals
+func regexpLiterals(re *syntax.Regexp) (minLen int, literals []string) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase == 0 {
+			s := string(re.Rune)
+			return len(s), []string{s}
+		}
+		// case-insensitive rune can be encoded with another bytes len, so use runes count
+		return len(re.Rune), nil
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return 1, nil
+	case syntax.OpCapture, syntax.OpPlus:
+		return regexpLiterals(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			minLen, literals = regexpLiterals(re.Sub[0])
+			return minLen * re.Min, literals
+		}
+	case syntax.OpConcat:
+		var run strings.Builder
+		for _, sub := range re.Sub {
+			if isLiteral(sub) {
+				run.WriteString(string(sub.Rune))
+				minLen += len(string(sub.Rune))
+				continue
+			}
+			if run.Len() > 0 {
+				literals = append(literals, run.String())
+				run.Reset()
+			}
+			subLen, subLiterals := regexpLiterals(sub)
+			minLen += subLen
+			literals = append(literals, subLiterals...)
+		}
+		if run.Len() > 0 {
+			literals = append(literals, run.String())
+		}
+	case syntax.OpAlternate:
+		for i, sub := range re.Sub {
+			subLen, _ := regexpLiterals(sub)
+			if i == 0 || subLen < minLen {
+				minLen = subLen
+			}
+		}
+	}
+	return
+}
+
+// TreeStats is a GTagsTree statistic
+type TreeStats struct {
+	Terms             int    // terms (tree nodes) count
+	Interned          int    // distinct (interned) terms count
+	Prefilters        int    // distinct terms with prefilter
+	PrefilterChecked  uint64 // values, checked by prefilters (if GTagsTree.PrefilterStats is enabled)
+	PrefilterRejected uint64 // values, rejected by prefilters (regexp/glob engine is not runned)
+}
+
+func (s *TreeStats) add(item *TaggedItem, visited map[*TaggedTerm]struct{}) {
+	s.Terms++
+	if _, ok := visited[item.Term]; !ok {
+		// interned terms are shared across tree nodes
+		visited[item.Term] = struct{}{}
+		if item.Term.Prefilter != nil {
+			s.Prefilters++
+			checked, rejected := item.Term.Prefilter.Stats()
+			s.PrefilterChecked += checked
+			s.PrefilterRejected += rejected
+		}
+	}
+	s.walk(item, visited)
+}
+
+func (s *TreeStats) walk(item *TaggedItem, visited map[*TaggedTerm]struct{}) {
+	for i := range item.Items {
+		for _, child := range item.Items[i].MatchedMap {
+			s.add(child, visited)
+		}
+		for _, child := range item.Items[i].Matched {
+			s.add(child, visited)
+		}
+		for _, child := range item.Items[i].NotMatched {
+			s.add(child, visited)
+		}
+	}
+	for _, child := range item.KeyItems {
+		s.add(child, visited)
+	}
+}
+
+// Stats return tree statistic (terms and prefilters counters)
+func (gtree *GTagsTree) Stats() (stats TreeStats) {
+	stats.Interned = len(gtree.Terms)
+	stats.walk(gtree.Root, make(map[*TaggedTerm]struct{}))
+	return
+}
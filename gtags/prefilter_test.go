@@ -0,0 +1,264 @@
+package gtags
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/msaf1980/go-matcher/gglob"
+	"github.com/msaf1980/go-matcher/glob"
+	"github.com/msaf1980/go-matcher/pkg/items"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegexpPrefilter(t *testing.T) {
+	tests := []struct {
+		re   string
+		want *Prefilter
+		pass []string
+		miss []string
+	}{
+		{re: ".*", want: nil},
+		{re: "a|b", want: &Prefilter{MinLen: 1, MaxLen: -1}, pass: []string{"a", "xyz"}, miss: []string{""}},
+		{
+			re:   "abc",
+			want: &Prefilter{Contains: []string{"abc"}, MinLen: 3, MaxLen: -1},
+			pass: []string{"abc", "xabcx"}, miss: []string{"ab", "abd", "bcab"},
+		},
+		{
+			re:   "^qaz-(kjv|wsc)xx",
+			want: &Prefilter{Prefix: "qaz-", Contains: []string{"xx"}, MinLen: 9, MaxLen: -1},
+			pass: []string{"qaz-kjvxx", "qaz-abcxx"}, miss: []string{"qaz-kjv", "xqaz-kjvxx", "qaz-kjvx"},
+		},
+		{
+			re:   "^(?:a.*b)$",
+			want: &Prefilter{Prefix: "a", Suffix: "b", MinLen: 2, MaxLen: -1},
+			pass: []string{"ab", "axb"}, miss: []string{"ba", "abx", "xab"},
+		},
+		{
+			re:   `^(?:prod|stage)$`,
+			want: &Prefilter{MinLen: 4, MaxLen: -1},
+			pass: []string{"prod", "stage", "other"}, miss: []string{"pro"},
+		},
+		{
+			re:   `cpu\.(user|sys)+\.load{2}`,
+			want: &Prefilter{Contains: []string{"cpu.", ".loa", "dd"}, MinLen: 13, MaxLen: -1},
+			pass: []string{"cpu.user.loadd"}, miss: []string{"cpu.user.load", "cpu.us.loadd"},
+		},
+		{
+			// case insensitive
+			re:   `(?i)abc`,
+			want: &Prefilter{MinLen: 3, MaxLen: -1},
+			pass: []string{"ABC", "abc"}, miss: []string{"ab"},
+		},
+		{
+			// multiline
+			re:   `(?m)^abc$`,
+			want: &Prefilter{Contains: []string{"abc"}, MinLen: 3, MaxLen: -1},
+			pass: []string{"x\nabc"}, miss: []string{"ab\nc"},
+		},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.re, func(t *testing.T) {
+			got := regexpPrefilter(tt.re)
+			assert.Equal(t, tt.want, got)
+			if got == nil {
+				return
+			}
+			got.Count = true
+			for _, v := range tt.pass {
+				assert.True(t, got.Pass(v), v)
+			}
+			for _, v := range tt.miss {
+				assert.False(t, got.Pass(v), v)
+			}
+			checked, rejected := got.Stats()
+			assert.Equal(t, uint64(len(tt.pass)+len(tt.miss)), checked)
+			assert.Equal(t, uint64(len(tt.miss)), rejected)
+		})
+	}
+}
+
+func TestGlobPrefilter(t *testing.T) {
+	tests := []struct {
+		glob string
+		want *Prefilter
+	}{
+		{glob: "*", want: nil},
+		{glob: "a*b", want: &Prefilter{Prefix: "a", Suffix: "b", MinLen: 2, MaxLen: -1}},
+		{glob: "a?c", want: &Prefilter{Prefix: "a", Suffix: "c", MinLen: 3, MaxLen: 6}},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.glob, func(t *testing.T) {
+			g, err := glob.Parse(tt.glob)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, globPrefilter(g))
+		})
+	}
+}
+
+func TestGGlobPrefilter(t *testing.T) {
+	tests := []struct {
+		glob string
+		want *Prefilter
+		pass []string // values with trailing dot are matched by gglob too
+		miss []string
+	}{
+		{glob: "*", want: nil},
+		{glob: "a.b", want: nil},
+		{glob: "*.*", want: &Prefilter{MinLen: 1, MaxLen: -1, TrimDot: true}},
+		{glob: "a.b*c.d", want: &Prefilter{Prefix: "a.b", Suffix: "c.d", MinLen: 6, MaxLen: -1, TrimDot: true}},
+		{glob: "a.*.c.d?", want: &Prefilter{Prefix: "a.", Suffix: "", MinLen: 7, MaxLen: -1, TrimDot: true}},
+		{glob: "a.b.*.c.d", want: &Prefilter{Prefix: "a.b.", Suffix: ".c.d", MinLen: 8, MaxLen: -1, TrimDot: true}},
+		{
+			glob: "*.b", want: &Prefilter{Suffix: ".b", MinLen: 2, MaxLen: -1, TrimDot: true},
+			pass: []string{"a.b", "a.b."}, miss: []string{"a.b..", "a.c."},
+		},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.glob, func(t *testing.T) {
+			g, err := gglob.Parse(tt.glob)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := gglobPrefilter(g)
+			assert.Equal(t, tt.want, p)
+			for _, v := range tt.pass {
+				assert.True(t, p.Pass(v), v)
+				assert.True(t, g.Match(v), v)
+			}
+			for _, v := range tt.miss {
+				assert.False(t, p.Pass(v), v)
+			}
+		})
+	}
+
+	// prefilter must not change match result
+	gtree := NewTreeDialect(DialectNameGGlob)
+	query := "seriesByTag('name=*.b')"
+	if _, _, err := gtree.Add(query, 0); err != nil {
+		t.Fatalf("GTagsTree.Add(%q) error = %v", query, err)
+	}
+	term := gtree.Terms["__name__=*.b"+termKindGGlob]
+	for _, v := range []string{"a.b", "a.b.", "x.y.b.", "a.c.", "a.b.c"} {
+		assert.Equal(t, term.GGlob.Match(v), term.Match(v), v)
+		var store items.IndexStore
+		gtree.MatchByTags([]Tag{{Key: "__name__", Value: v}}, &store)
+		assert.Equal(t, term.GGlob.Match(v), len(store.N) == 1, v)
+	}
+}
+
+func TestGTagsTree_Stats(t *testing.T) {
+	gtree := NewTree()
+	gtree.PrefilterStats = true
+	queries := []string{
+		"seriesByTag('name=a', 'b=~^qaz-.*x$')",
+		"seriesByTag('name=a', 'b!=~^qaz-')",
+		"seriesByTag('name=a', 'c=d*e')",
+		"seriesByTag('name=a', 'c=d')",
+	}
+	for i, query := range queries {
+		if _, _, err := gtree.Add(query, i); err != nil {
+			t.Fatalf("GTagsTree.Add(%q) error = %v", query, err)
+		}
+	}
+	for path, want := range map[string][]int{
+		"a?b=qaz-yx&c=dxe": {0, 2},
+		"a?b=wsx-yx&c=d":   {1, 3},
+		"a?b=qaz-yz&c=ex":  nil,
+	} {
+		tags, err := PathTags(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var store items.IndexStore
+		gtree.MatchByTags(tags, &store)
+		assert.ElementsMatch(t, want, store.N, path)
+	}
+	assert.Equal(t, TreeStats{Terms: 5, Interned: 5, Prefilters: 3, PrefilterChecked: 9, PrefilterRejected: 5}, gtree.Stats())
+}
+
+func TestGTagsTree_Stats_Disabled(t *testing.T) {
+	gtree := NewTree()
+	query := "seriesByTag('name=a', 'b=~^qaz-.*x$')"
+	if _, _, err := gtree.Add(query, 0); err != nil {
+		t.Fatalf("GTagsTree.Add(%q) error = %v", query, err)
+	}
+	tags, err := PathTags("a?b=qaz-yz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var store items.IndexStore
+	gtree.MatchByTags(tags, &store)
+	assert.Equal(t, 0, len(store.N))
+	assert.Equal(t, TreeStats{Terms: 2, Interned: 2, Prefilters: 1}, gtree.Stats())
+}
+
+func benchmarkTaggedTermMatch(b *testing.B, prefilter bool) {
+	terms, err := ParseSeriesByTag("seriesByTag('UHVWER=~qaz-wscx-wscxx|qaz-wscx-wscy|qaz-wscx-wscr|qaz-wscxxx|qaz-wscxy')")
+	if err != nil {
+		b.Fatal(err)
+	}
+	term := terms[0]
+	if !prefilter {
+		term.Prefilter = nil
+	}
+	values := []string{"qaz-kjvxx", "qaz-qxzbotxx", "abc", "qaz-wscxy", "pfjl-ddsn"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, v := range values {
+			_ = term.Match(v)
+		}
+	}
+}
+
+func BenchmarkTaggedTerm_Match_Regexp(b *testing.B) {
+	benchmarkTaggedTermMatch(b, false)
+}
+
+func BenchmarkTaggedTerm_Match_Prefilter(b *testing.B) {
+	benchmarkTaggedTermMatch(b, true)
+}
+
+func benchmarkGTagsTreeMatchParallel(b *testing.B, stats bool) {
+	gtree := NewTree()
+	gtree.PrefilterStats = stats
+	queries := []string{
+		"seriesByTag('name=a', 'b=~^qaz-.*x$')",
+		"seriesByTag('name=a', 'b!=~^qaz-')",
+		"seriesByTag('name=a', 'c=d*e')",
+		"seriesByTag('name=a', 'c=~wsx|edc')",
+	}
+	for i, query := range queries {
+		if _, _, err := gtree.Add(query, i); err != nil {
+			b.Fatalf("GTagsTree.Add(%q) error = %v", query, err)
+		}
+	}
+	var tagsList [][]Tag
+	for _, path := range []string{"a?b=qaz-yx&c=dxe", "a?b=wsx-yx&c=d", "a?b=qaz-yz&c=ex", "a?b=qaz-yx&c=wsx"} {
+		tags, err := PathTags(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		tagsList = append(tagsList, tags)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var store items.IndexStore
+		for pb.Next() {
+			for _, tags := range tagsList {
+				store.Init()
+				gtree.MatchByTags(tags, &store)
+			}
+		}
+	})
+}
+
+func BenchmarkGTagsTree_MatchParallel(b *testing.B) {
+	benchmarkGTagsTreeMatchParallel(b, false)
+}
+
+func BenchmarkGTagsTree_MatchParallel_PrefilterStats(b *testing.B) {
+	benchmarkGTagsTreeMatchParallel(b, true)
+}
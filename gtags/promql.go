@@ -0,0 +1,335 @@
+package gtags
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/msaf1980/go-matcher/gglob"
+	"github.com/msaf1980/go-matcher/glob"
+	"github.com/msaf1980/go-matcher/pkg/items"
+)
+
+func isLabelStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+func isLabelChar(c byte) bool {
+	return isLabelStart(c) || (c >= '0' && c <= '9')
+}
+
+func isMetricStart(c byte) bool {
+	return isLabelStart(c) || c == ':'
+}
+
+func isMetricChar(c byte) bool {
+	return isLabelChar(c) || c == ':'
+}
+
+// IsLabelName check for valid Prometheus label name
+func IsLabelName(s string) bool {
+	if s == "" || !isLabelStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isLabelChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsMetricName check for valid Prometheus metric name
+func IsMetricName(s string) bool {
+	if s == "" || !isMetricStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isMetricChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// parsePromQLString parse PromQL string literal (quoted with ", ' or `) at pos and return unescaped value and next position
+func parsePromQLString(selector string, pos int) (value string, next int, err error) {
+	quote := selector[pos]
+	if quote == '`' {
+		// raw string
+		end := strings.IndexByte(selector[pos+1:], '`')
+		if end == -1 {
+			err = ErrSelectorSyntax{Selector: selector, Offset: pos, Reason: "unterminated string"}
+			return
+		}
+		end += pos + 1
+		return selector[pos+1 : end], end + 1, nil
+	}
+
+	s := selector[pos+1:]
+	if end := strings.IndexByte(s, quote); end != -1 && strings.IndexByte(s[:end], '\\') == -1 {
+		// fast path, without escapes
+		return s[:end], pos + end + 2, nil
+	}
+	var buf strings.Builder
+	buf.Grow(len(s))
+	for len(s) > 0 {
+		if s[0] == quote {
+			return buf.String(), len(selector) - len(s) + 1, nil
+		}
+		var (
+			c         rune
+			multibyte bool
+			tail      string
+		)
+		if c, multibyte, tail, err = strconv.UnquoteChar(s, quote); err != nil {
+			err = ErrSelectorSyntax{Selector: selector, Offset: len(selector) - len(s), Reason: "invalid escape sequence"}
+			return
+		}
+		s = tail
+		if multibyte || c >= 0x80 {
+			buf.WriteRune(c)
+		} else {
+			buf.WriteByte(byte(c))
+		}
+	}
+	err = ErrSelectorSyntax{Selector: selector, Offset: pos, Reason: "unterminated string"}
+	return
+}
+
+// promQLTerm return term with Prometheus semantics (regexps are anchored at both ends, values are literal)
+func promQLTerm(key string, op TaggedTermOp, value string) (term TaggedTerm, err error) {
+	if (op == TaggedTermEq || op == TaggedTermNe) && items.HasWildcard(value) {
+		// literal with glob symbols, convert to regexp
+		if op == TaggedTermEq {
+			op = TaggedTermMatch
+		} else {
+			op = TaggedTermNotMatch
+		}
+		value = regexp.QuoteMeta(value)
+	}
+	term = TaggedTerm{Key: key, Op: op, Value: value}
+	err = term.build(DialectPrometheus)
+	return
+}
+
+// ParsePromQLSelector parse PromQL vector selector (like metric{job="a",env=~"prod|stage",dc!="x"}) into terms.
+// Regexps are anchored at both ends (Prometheus semantics), literal values with glob symbols are converted to regexps.
+func ParsePromQLSelector(selector string) (terms TaggedTermList, err error) {
+	pos := skipSpaces(selector, 0)
+	if pos < len(selector) && isMetricStart(selector[pos]) {
+		start := pos
+		for pos < len(selector) && isMetricChar(selector[pos]) {
+			pos++
+		}
+		var term TaggedTerm
+		if term, err = promQLTerm("__name__", TaggedTermEq, selector[start:pos]); err != nil {
+			return
+		}
+		terms = append(terms, term)
+		pos = skipSpaces(selector, pos)
+	}
+
+	if pos < len(selector) && selector[pos] == '{' {
+		pos = skipSpaces(selector, pos+1)
+		for pos < len(selector) && selector[pos] != '}' {
+			// label name
+			start := pos
+			if !isLabelStart(selector[pos]) {
+				err = ErrSelectorSyntax{Selector: selector, Offset: pos, Reason: "label name expected"}
+				return
+			}
+			for pos < len(selector) && isLabelChar(selector[pos]) {
+				pos++
+			}
+			key := selector[start:pos]
+			pos = skipSpaces(selector, pos)
+
+			// operator
+			var op TaggedTermOp
+			switch {
+			case strings.HasPrefix(selector[pos:], "=~"):
+				op = TaggedTermMatch
+				pos += 2
+			case strings.HasPrefix(selector[pos:], "!~"):
+				op = TaggedTermNotMatch
+				pos += 2
+			case strings.HasPrefix(selector[pos:], "!="):
+				op = TaggedTermNe
+				pos += 2
+			case strings.HasPrefix(selector[pos:], "="):
+				op = TaggedTermEq
+				pos++
+			default:
+				err = ErrSelectorSyntax{Selector: selector, Offset: pos, Reason: "label matcher operator expected"}
+				return
+			}
+			pos = skipSpaces(selector, pos)
+
+			// value
+			if pos == len(selector) || (selector[pos] != '"' && selector[pos] != '\'' && selector[pos] != '`') {
+				err = ErrSelectorSyntax{Selector: selector, Offset: pos, Reason: "string expected"}
+				return
+			}
+			var value string
+			if value, pos, err = parsePromQLString(selector, pos); err != nil {
+				return
+			}
+			var term TaggedTerm
+			if term, err = promQLTerm(key, op, value); err != nil {
+				return
+			}
+			terms = append(terms, term)
+
+			pos = skipSpaces(selector, pos)
+			if pos < len(selector) && selector[pos] == ',' {
+				pos = skipSpaces(selector, pos+1)
+			} else if pos < len(selector) && selector[pos] != '}' {
+				err = ErrSelectorSyntax{Selector: selector, Offset: pos, Reason: "',' or '}' expected"}
+				return
+			}
+		}
+		if pos == len(selector) {
+			err = ErrSelectorSyntax{Selector: selector, Offset: pos, Reason: "'}' expected"}
+			return
+		}
+		pos = skipSpaces(selector, pos+1)
+	}
+
+	if pos != len(selector) {
+		err = ErrSelectorSyntax{Selector: selector, Offset: pos, Reason: "unexpected symbols"}
+		return
+	}
+	if len(terms) == 0 {
+		err = ErrSelectorSyntax{Selector: selector, Offset: pos, Reason: "empty selector"}
+		return
+	}
+
+	terms.sort()
+
+	return
+}
+
+// regexpGroupEnd return position of closing parenthesis for group, started at start
+func regexpGroupEnd(re string, start int) int {
+	depth := 0
+	for i := start; i < len(re); i++ {
+		switch re[i] {
+		case '\\':
+			i++
+		case '[':
+			// skip char class
+			i++
+			if i < len(re) && re[i] == '^' {
+				i++
+			}
+			if i < len(re) && re[i] == ']' {
+				i++
+			}
+			for ; i < len(re) && re[i] != ']'; i++ {
+				if re[i] == '\\' {
+					i++
+				}
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// promQLRegexp convert unanchored regexp to regexp, matched against the whole string (Prometheus semantics)
+func promQLRegexp(re string) string {
+	if strings.HasPrefix(re, "^(?:") && strings.HasSuffix(re, ")$") && regexpGroupEnd(re, 1) == len(re)-2 {
+		// anchored at both ends by dialect
+		return re[4 : len(re)-2]
+	}
+	if strings.HasPrefix(re, "^(?:") && regexpGroupEnd(re, 1) == len(re)-1 {
+		// anchored at start by dialect
+		return re + ".*"
+	}
+	if strings.HasPrefix(re, "^") && !strings.Contains(re, "|") {
+		if strings.HasSuffix(re, "$") && !strings.HasSuffix(re, `\$`) {
+			return re
+		}
+		return re + ".*"
+	}
+	if re == "" {
+		return ".*"
+	}
+	return ".*(?:" + re + ").*"
+}
+
+// WritePromQLSelector write terms as PromQL vector selector (glob values are converted to regexps)
+func (t TaggedTermList) WritePromQLSelector(buf *strings.Builder) (err error) {
+	start := 0
+	if len(t) > 0 && t[0].Key == "__name__" && t[0].Op == TaggedTermEq && !t[0].HasWildcard && IsMetricName(t[0].Value) {
+		buf.WriteString(t[0].Value)
+		start = 1
+	}
+	if start == len(t) {
+		if start == 0 {
+			buf.WriteString("{}")
+		}
+		return
+	}
+	buf.WriteByte('{')
+	for i := start; i < len(t); i++ {
+		term := &t[i]
+		if !IsLabelName(term.Key) {
+			return ErrLabelInvalid{term.Key}
+		}
+		if i > start {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(term.Key)
+		value := term.Value
+		switch term.Op {
+		case TaggedTermEq, TaggedTermNe:
+			if term.HasWildcard {
+				if term.GGlob != nil {
+					value, err = gglob.ToRegexp(value)
+				} else {
+					value, err = glob.ToRegexp(value)
+				}
+				if err != nil {
+					return
+				}
+				if term.Op == TaggedTermEq {
+					buf.WriteString("=~")
+				} else {
+					buf.WriteString("!~")
+				}
+			} else {
+				buf.WriteString(term.Op.String())
+			}
+		case TaggedTermMatch:
+			buf.WriteString("=~")
+			value = promQLRegexp(value)
+		case TaggedTermNotMatch:
+			buf.WriteString("!~")
+			value = promQLRegexp(value)
+		default:
+			return ErrExprInvalid{term.String()}
+		}
+		buf.WriteString(strconv.Quote(value))
+	}
+	buf.WriteByte('}')
+
+	return
+}
+
+// PromQLSelector return terms as PromQL vector selector (glob values are converted to regexps)
+func (t TaggedTermList) PromQLSelector() (string, error) {
+	var buf strings.Builder
+	buf.Grow(24 * len(t))
+	if err := t.WritePromQLSelector(&buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,203 @@
+package gtags
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/msaf1980/go-matcher/glob"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePromQLSelector(t *testing.T) {
+	tests := []struct {
+		selector   string
+		wantQuery  string
+		wantPromQL string
+		matchPaths []string
+		missPaths  []string
+	}{
+		{
+			selector:   `metric{job="a",env=~"prod|stage",dc!="x"}`,
+			wantQuery:  `seriesByTag('__name__=metric','dc!=x','env=~^(?:prod|stage)$','job=a')`,
+			wantPromQL: `metric{dc!="x",env=~"prod|stage",job="a"}`,
+			matchPaths: []string{"metric?dc=y&env=prod&job=a", "metric?dc=z&env=stage&job=a"},
+			missPaths: []string{
+				"metric?dc=x&env=prod&job=a", "metric?env=production&job=a", "metric?env=preprod&job=a",
+				"metric?env=prod&job=ab", "metric2?env=prod&job=a",
+			},
+		},
+		{
+			selector:   ` { __name__ = "metric" , job = 'a' , } `,
+			wantQuery:  `seriesByTag('__name__=metric','job=a')`,
+			wantPromQL: `metric{job="a"}`,
+			matchPaths: []string{"metric?job=a"},
+			missPaths:  []string{"metric?job=b"},
+		},
+		{
+			selector:   `metric`,
+			wantQuery:  `seriesByTag('__name__=metric')`,
+			wantPromQL: `metric`,
+			matchPaths: []string{"metric?job=a"},
+			missPaths:  []string{"metric2?job=a"},
+		},
+		{
+			selector:   "{job=~`a\\.b.*`, env!~\"te\\\\.st\"}",
+			wantQuery:  `seriesByTag('env!=~^(?:te\.st)$','job=~^(?:a\.b.*)$')`,
+			wantPromQL: `{env!~"te\\.st",job=~"a\\.b.*"}`,
+			matchPaths: []string{"m?env=te_st&job=a.bc", "m?env=test&job=a.b"},
+			missPaths:  []string{"m?env=te.st&job=a.bc", "m?job=aab", "m?job=x.a.b"},
+		},
+		{
+			// literal with glob symbols
+			selector:   `{job="a*b", env!="t?st"}`,
+			wantQuery:  `seriesByTag('env!=~^(?:t\?st)$','job=~^(?:a\*b)$')`,
+			wantPromQL: `{env!~"t\\?st",job=~"a\\*b"}`,
+			matchPaths: []string{"m?env=test&job=a%2Ab"},
+			missPaths:  []string{"m?job=ab", "m?env=t%3Fst&job=a%2Ab"},
+		},
+		{
+			selector:   `{job="it's \"q\""}`,
+			wantQuery:  `seriesByTag('job=it\'s "q"')`,
+			wantPromQL: `{job="it's \"q\""}`,
+			matchPaths: []string{"m?job=it%27s+%22q%22"},
+			missPaths:  []string{"m?job=its"},
+		},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.selector, func(t *testing.T) {
+			terms, err := ParsePromQLSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("ParsePromQLSelector(%q) error = %v", tt.selector, err)
+			}
+			assert.Equal(t, tt.wantQuery, terms.String())
+			verifyTaggedTermList(t, tt.matchPaths, tt.missPaths, terms)
+
+			promql, err := terms.PromQLSelector()
+			if err != nil {
+				t.Fatalf("PromQLSelector(%q) error = %v", tt.wantQuery, err)
+			}
+			assert.Equal(t, tt.wantPromQL, promql)
+
+			// round-trip
+			terms2, err := ParsePromQLSelector(promql)
+			if err != nil {
+				t.Fatalf("ParsePromQLSelector(%q) error = %v", promql, err)
+			}
+			verifyTaggedTermList(t, tt.matchPaths, tt.missPaths, terms2)
+
+			gtree := NewTree()
+			normalized, _, err := gtree.AddTerms(terms, 0)
+			if err != nil {
+				t.Fatalf("AddTerms(%q) error = %v", tt.wantQuery, err)
+			}
+			normalized2, n, err := gtree.AddTerms(terms2, 1)
+			assert.Equal(t, glob.ErrGlobExist, err)
+			assert.Equal(t, tt.wantQuery, normalized)
+			assert.Equal(t, normalized, normalized2)
+			assert.Equal(t, 0, n)
+		})
+	}
+}
+
+func TestParsePromQLSelector_Error(t *testing.T) {
+	tests := []struct {
+		selector string
+		wantErr  error
+	}{
+		{selector: "", wantErr: ErrSelectorSyntax{Selector: "", Offset: 0, Reason: "empty selector"}},
+		{selector: "{}", wantErr: ErrSelectorSyntax{Selector: "{}", Offset: 2, Reason: "empty selector"}},
+		{selector: `{1a="b"}`, wantErr: ErrSelectorSyntax{Selector: `{1a="b"}`, Offset: 1, Reason: "label name expected"}},
+		{selector: `{a<"b"}`, wantErr: ErrSelectorSyntax{Selector: `{a<"b"}`, Offset: 2, Reason: "label matcher operator expected"}},
+		{selector: `{a=b}`, wantErr: ErrSelectorSyntax{Selector: `{a=b}`, Offset: 3, Reason: "string expected"}},
+		{selector: `{a="b}`, wantErr: ErrSelectorSyntax{Selector: `{a="b}`, Offset: 3, Reason: "unterminated string"}},
+		{selector: `{a="b\q"}`, wantErr: ErrSelectorSyntax{Selector: `{a="b\q"}`, Offset: 5, Reason: "invalid escape sequence"}},
+		{selector: `{a="b" c="d"}`, wantErr: ErrSelectorSyntax{Selector: `{a="b" c="d"}`, Offset: 7, Reason: "',' or '}' expected"}},
+		{selector: `{a="b"`, wantErr: ErrSelectorSyntax{Selector: `{a="b"`, Offset: 6, Reason: "'}' expected"}},
+		{selector: `m{a="b"} x`, wantErr: ErrSelectorSyntax{Selector: `m{a="b"} x`, Offset: 9, Reason: "unexpected symbols"}},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.selector, func(t *testing.T) {
+			_, err := ParsePromQLSelector(tt.selector)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestTaggedTermList_PromQLSelector(t *testing.T) {
+	tests := []struct {
+		query      string
+		dialect    Dialect
+		wantPromQL string
+		wantErr    error
+		matchPaths []string
+		missPaths  []string
+	}{
+		{
+			query:      `seriesByTag('name=a.*', 'b=c{d,e}[0-9]?', 'f!=g*')`,
+			wantPromQL: `{__name__=~"a\\..*",b=~"c(?:d|e)[0-9].",f!~"g.*"}`,
+			matchPaths: []string{"a.b?b=cd1x&f=h", "a.?b=ce0z"},
+			missPaths:  []string{"a.b?b=cd1&f=h", "a.b?b=cd1x&f=gh", "ab?b=cd1x"},
+		},
+		{
+			query:      `seriesByTag('name=a.*', 'b=c*')`,
+			dialect:    DialectGraphiteClickHouse | DialectNameGGlob,
+			wantPromQL: `{__name__=~"a\\.[^.]*",b=~"c.*"}`,
+			matchPaths: []string{"a.b?b=c.d"},
+			missPaths:  []string{"a.b.c?b=c"},
+		},
+		{
+			query:      `seriesByTag('name=a', 'b=~c.*d')`,
+			wantPromQL: `a{b=~".*(?:c.*d).*"}`,
+			matchPaths: []string{"a?b=cd", "a?b=xcxdx"},
+			missPaths:  []string{"a?b=dc"},
+		},
+		{
+			query:      `seriesByTag('name=a', 'b=~^c', 'd!=~^e$')`,
+			wantPromQL: `a{b=~"^c.*",d!~"^e$"}`,
+			matchPaths: []string{"a?b=cd&d=ee", "a?b=c"},
+			missPaths:  []string{"a?b=dc", "a?b=c&d=e"},
+		},
+		{
+			query:      `seriesByTag('name=a', 'b=~c|d')`,
+			dialect:    DialectGraphiteWeb,
+			wantPromQL: `a{b=~"^(?:c|d).*"}`,
+			matchPaths: []string{"a?b=c", "a?b=dx"},
+			missPaths:  []string{"a?b=xc"},
+		},
+		{
+			query:      `seriesByTag('name=a', 'b=~(c)|(d)')`,
+			dialect:    DialectPrometheus,
+			wantPromQL: `a{b=~"(c)|(d)"}`,
+			matchPaths: []string{"a?b=c", "a?b=d"},
+			missPaths:  []string{"a?b=cd", "a?b=xc"},
+		},
+		{
+			query:   `seriesByTag('name=a', 'b.c=d')`,
+			wantErr: ErrLabelInvalid{"b.c"},
+		},
+	}
+	for n, tt := range tests {
+		t.Run(strconv.Itoa(n)+"#"+tt.query, func(t *testing.T) {
+			terms, err := ParseSeriesByTagDialect(tt.query, tt.dialect)
+			if err != nil {
+				t.Fatalf("ParseSeriesByTag(%q) error = %v", tt.query, err)
+			}
+			promql, err := terms.PromQLSelector()
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("PromQLSelector(%q) error = %v", tt.query, err)
+			}
+			assert.Equal(t, tt.wantPromQL, promql)
+			verifyTaggedTermList(t, tt.matchPaths, tt.missPaths, terms)
+
+			terms2, err := ParsePromQLSelector(promql)
+			if err != nil {
+				t.Fatalf("ParsePromQLSelector(%q) error = %v", promql, err)
+			}
+			verifyTaggedTermList(t, tt.matchPaths, tt.missPaths, terms2)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package gtags
+
+import (
+	"strings"
+
+	"github.com/msaf1980/go-matcher/pkg/escape"
+)
+
+// TagSource is a tags container for match (like labels in user struct), absent tag is an empty value
+type TagSource interface {
+	// Get return tag value (false if tag not exist)
+	Get(key string) (value string, ok bool)
+}
+
+// SortedTagSource is an optional TagSource extension with access to tags, sorted by key (__name__ is first),
+// used for faster match without keys lookup
+type SortedTagSource interface {
+	TagSource
+	// SortedTags return sorted tags (must not be modified)
+	SortedTags() []Tag
+}
+
+// RangeTagSource is an optional TagSource extension for iterate over all tags, used for wildcard (regexp) keys terms match
+type RangeTagSource interface {
+	TagSource
+	// Range call f for each tag, until f return false
+	Range(f func(key, value string) bool)
+}
+
+// TagSliceSource is a TagSource adapter for tags slice, sorted by key (__name__ is first, use SortTags)
+type TagSliceSource []Tag
+
+func (tags TagSliceSource) Get(key string) (string, bool) {
+	if n := find(tags, key, 0); n != -1 {
+		return tags[n].Value, true
+	}
+	return "", false
+}
+
+func (tags TagSliceSource) SortedTags() []Tag {
+	return tags
+}
+
+// TagMapSource is a TagSource adapter for tags map
+type TagMapSource map[string]string
+
+func (tags TagMapSource) Get(key string) (value string, ok bool) {
+	value, ok = tags[key]
+	return
+}
+
+// GraphitePathSource is a TagSource adapter for Graphite tagged path (like name;a=v1;b=v2), path is scanned on every Get
+// (without allocations, if path is not escaped)
+type GraphitePathSource string
+
+func (path GraphitePathSource) Get(key string) (string, bool) {
+	name, args, _ := strings.Cut(string(path), ";")
+	if key == "__name__" {
+		if name == "" {
+			return "", false
+		}
+		return escape.Unescape(name), true
+	}
+	for args != "" {
+		var kv string
+		kv, args, _ = strings.Cut(args, ";")
+		if k, v, ok := strings.Cut(kv, "="); ok && escape.Unescape(k) == key {
+			return escape.Unescape(v), true
+		}
+	}
+	return "", false
+}
+
+func (path GraphitePathSource) Range(f func(key, value string) bool) {
+	name, args, _ := strings.Cut(string(path), ";")
+	if name != "" && !f("__name__", escape.Unescape(name)) {
+		return
+	}
+	for args != "" {
+		var kv string
+		kv, args, _ = strings.Cut(args, ";")
+		if k, v, ok := strings.Cut(kv, "="); ok && !f(escape.Unescape(k), escape.Unescape(v)) {
+			return
+		}
+	}
+}
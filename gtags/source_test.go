@@ -0,0 +1,132 @@
+package gtags
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/msaf1980/go-matcher/pkg/items"
+	"github.com/stretchr/testify/assert"
+)
+
+// labels is a user labels container
+type labels struct {
+	name string
+	host string
+	dc   string
+}
+
+func (l *labels) Get(key string) (string, bool) {
+	switch key {
+	case "__name__":
+		return l.name, l.name != ""
+	case "host":
+		return l.host, l.host != ""
+	case "dc":
+		return l.dc, l.dc != ""
+	}
+	return "", false
+}
+
+func TestTagSource_Get(t *testing.T) {
+	path := "a%2Eb;c=d;e%3D=f%3B;g="
+	tags, err := GraphitePathTagsB(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := map[string]TagSource{
+		"TagSliceSource":     TagSliceSource(tags),
+		"TagMapSource":       TagMapSource(TagsMap(tags)),
+		"GraphitePathSource": GraphitePathSource(path),
+	}
+	for name, src := range sources {
+		t.Run(name, func(t *testing.T) {
+			for key, want := range map[string]string{"__name__": "a.b", "c": "d", "e=": "f;", "g": ""} {
+				v, ok := src.Get(key)
+				assert.True(t, ok, key)
+				assert.Equal(t, want, v, key)
+			}
+			v, ok := src.Get("h")
+			assert.False(t, ok)
+			assert.Equal(t, "", v)
+		})
+	}
+}
+
+func TestGTagsTree_MatchBySource(t *testing.T) {
+	queries := []string{
+		"seriesByTag('name=cpu', 'host=~^h')",
+		"seriesByTag('name=cpu', 'dc=')",
+		"seriesByTag('name=cpu', 'dc!=', 'host!=h1')",
+		"seriesByTag('name=~^c', 'dc=d*')",
+	}
+	gtree := NewTree()
+	termsList := make([]TaggedTermList, len(queries))
+	for i, query := range queries {
+		var err error
+		if termsList[i], err = ParseSeriesByTag(query); err != nil {
+			t.Fatalf("ParseSeriesByTag(%q) error = %v", query, err)
+		}
+		if _, _, err = gtree.Add(query, i); err != nil {
+			t.Fatalf("GTagsTree.Add(%q) error = %v", query, err)
+		}
+	}
+	tests := []struct {
+		labels labels
+		want   []int
+	}{
+		{labels: labels{name: "cpu", host: "h1"}, want: []int{0, 1}},
+		{labels: labels{name: "cpu", host: "h2", dc: "dc1"}, want: []int{0, 2, 3}},
+		{labels: labels{name: "cpu", host: "x", dc: "x"}, want: []int{2}},
+		{labels: labels{name: "mem", host: "h1", dc: "dc1"}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.labels.name+"#"+tt.labels.host+"#"+tt.labels.dc, func(t *testing.T) {
+			tags := []Tag{{Key: "__name__", Value: tt.labels.name}, {Key: "dc", Value: tt.labels.dc}, {Key: "host", Value: tt.labels.host}}
+			path := tt.labels.name + ";host=" + tt.labels.host + ";dc=" + tt.labels.dc
+			sources := map[string]TagSource{
+				"labels":             &tt.labels,
+				"TagSliceSource":     TagSliceSource(tags),
+				"TagMapSource":       TagMapSource(TagsMap(tags)),
+				"GraphitePathSource": GraphitePathSource(path),
+			}
+			for name, src := range sources {
+				var got []int
+				for i, terms := range termsList {
+					if terms.MatchBySource(src) {
+						got = append(got, i)
+					}
+				}
+				assert.Equal(t, tt.want, got, "TaggedTermList.MatchBySource(%s)", name)
+
+				var store items.IndexStore
+				n := gtree.MatchBySource(src, &store)
+				sort.Ints(store.N)
+				assert.Equal(t, tt.want, store.N, "GTagsTree.MatchBySource(%s)", name)
+				assert.Equal(t, len(tt.want), n, "GTagsTree.MatchBySource(%s)", name)
+			}
+		})
+	}
+}
+
+func TestGTagsTree_MatchRoot(t *testing.T) {
+	gtree := NewTree()
+	if _, _, err := gtree.Add("seriesByTag()", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := gtree.Add("seriesByTag('name=a')", 1); err != nil {
+		t.Fatal(err)
+	}
+	tags := []Tag{{Key: "__name__", Value: "a"}}
+
+	var store items.IndexStore
+	assert.Equal(t, 2, gtree.MatchByTags(tags, &store))
+	assert.Equal(t, []int{0, 1}, store.N)
+
+	store.N = nil
+	assert.Equal(t, 2, gtree.MatchByTagsMap(TagsMap(tags), &store))
+	assert.Equal(t, []int{0, 1}, store.N)
+
+	store.N = nil
+	assert.Equal(t, 2, gtree.MatchBySource(GraphitePathSource("a"), &store))
+	assert.Equal(t, []int{0, 1}, store.N)
+}
@@ -2,10 +2,13 @@ package gtags
 
 import (
 	"fmt"
+	"math"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/msaf1980/go-matcher/gglob"
 	"github.com/msaf1980/go-matcher/glob"
 	"github.com/msaf1980/go-matcher/pkg/escape"
 	"github.com/msaf1980/go-matcher/pkg/items"
@@ -20,23 +23,40 @@ const (
 	TaggedTermMatch    TaggedTermOp = 2 // =~
 	TaggedTermNe       TaggedTermOp = 3 // !=
 	TaggedTermNotMatch TaggedTermOp = 4 // !=~
+	TaggedTermGt       TaggedTermOp = 5 // > (numeric)
+	TaggedTermGe       TaggedTermOp = 6 // >= (numeric)
+	TaggedTermLt       TaggedTermOp = 7 // < (numeric)
+	TaggedTermLe       TaggedTermOp = 8 // <= (numeric)
 )
 
 var (
-	stringsTaggedTermOp = []string{"none", "=", "=~", "!=", "!=~"}
+	stringsTaggedTermOp = []string{"none", "=", "=~", "!=", "!=~", ">", ">=", "<", "<="}
 )
 
 func (t TaggedTermOp) String() string {
 	return stringsTaggedTermOp[t]
 }
 
+// IsNumeric check for numeric comparison op (>, >=, < or <=)
+func (t TaggedTermOp) IsNumeric() bool {
+	return t >= TaggedTermGt && t <= TaggedTermLe
+}
+
 type TaggedTerm struct {
-	Key         string
+	Key         string         // tag key (glob for wildcard key or ~regexp for regexp key)
+	KeyGlob     *glob.Glob     // key glob matcher (for wildcard key, like k8s_*)
+	KeyRe       *regexp.Regexp // key regexp matcher (for key like ~^debug)
 	Op          TaggedTermOp
 	Value       string
 	HasWildcard bool           // only for TaggedTermEq
 	Glob        *glob.Glob     // glob macher if HasWildcard
+	GGlob       *gglob.GGlob   // dot-aware glob macher for __name__ if HasWildcard (with DialectNameGGlob)
 	Re          *regexp.Regexp // regexp
+	Prefilter   *Prefilter     // required literals check for regexp/glob (nil if nothing to check)
+	Number      float64        // parsed value for numeric ops
+
+	id    int  // interned term id in GTagsTree (from 1), used for match memoization
+	empty bool // regexp match empty value
 }
 
 func (t TaggedTerm) WriteString(buf *strings.Builder) {
@@ -52,14 +72,41 @@ func (t TaggedTerm) String() string {
 	return buf.String()
 }
 
-// build compile regexp/glob
-func (term *TaggedTerm) build() (err error) {
-	if term.Op == TaggedTermMatch || term.Op == TaggedTermNotMatch {
+// build compile regexp/glob (regexp is rewrited with dialect anchoring semantics)
+func (term *TaggedTerm) build(dialect Dialect) (err error) {
+	if term.KeyGlob == nil && term.KeyRe == nil {
+		if err = term.buildKey(dialect); err != nil {
+			return
+		}
+	}
+	if term.Op.IsNumeric() {
+		if term.Number, err = strconv.ParseFloat(term.Value, 64); err != nil || math.IsNaN(term.Number) {
+			return ErrExprInvalid{term.String()}
+		}
+		return
+	} else if term.Op == TaggedTermMatch || term.Op == TaggedTermNotMatch {
+		term.Value = dialect.anchorRegexp(term.Value)
 		term.Re, err = regexp.Compile(term.Value)
 		if err != nil {
 			err = ErrExprInvalid{term.Value}
+		} else {
+			term.Prefilter = regexpPrefilter(term.Value)
 		}
 	} else if items.HasWildcard(term.Value) {
+		if dialect&DialectLiteralEq != 0 {
+			// literal with glob symbols, compared as string
+			term.HasWildcard = false
+			return
+		}
+		if term.Key == "__name__" && dialect&DialectNameGGlob != 0 {
+			if term.GGlob, err = gglob.Parse(term.Value); err != nil {
+				return err
+			}
+			term.Value = term.GGlob.Node
+			term.HasWildcard = true
+			term.Prefilter = gglobPrefilter(term.GGlob)
+			return
+		}
 		term.HasWildcard = true
 		if term.Glob, err = glob.Parse(term.Value); err != nil {
 			return err
@@ -70,22 +117,73 @@ func (term *TaggedTerm) build() (err error) {
 			term.HasWildcard = false
 		} else {
 			term.HasWildcard = true
+			term.Prefilter = globPrefilter(term.Glob)
 		}
 	}
+	if err == nil && term.Re != nil {
+		term.empty = term.match("")
+	}
 	return
 }
 
+// interned key suffixes for terms, which string is the same as for glob term
+const (
+	termKindGGlob   = "\x00gglob"   // dot-aware name glob
+	termKindLiteral = "\x00literal" // literal with glob symbols
+)
+
+// kind return matcher kind suffix for interned terms key (terms with the same string can use different matchers)
+func (term *TaggedTerm) kind() string {
+	if term.GGlob != nil {
+		return termKindGGlob
+	}
+	if (term.Op == TaggedTermEq || term.Op == TaggedTermNe) && !term.HasWildcard && items.HasWildcard(term.Value) {
+		return termKindLiteral
+	}
+	return ""
+}
+
+// MatchEmpty check term for match empty value. In graphite semantics absent tag is an empty value,
+// so tag= match absent tag, tag!= match present tag and regexp, matched empty string, also match absent tag.
+// Glob (like tag=*) is matched only with present tag (like in graphite-clickhouse).
+func (term *TaggedTerm) MatchEmpty() bool {
+	if term.Re != nil {
+		return term.empty
+	}
+	switch term.Op {
+	case TaggedTermEq:
+		return term.Value == "" && !term.HasWildcard
+	case TaggedTermNe:
+		return term.Value != "" || term.HasWildcard
+	default:
+		return false
+	}
+}
+
+// Match check value (empty value is an absent tag)
 func (term *TaggedTerm) Match(v string) bool {
+	if v == "" {
+		return term.MatchEmpty()
+	}
+	if term.Prefilter != nil && !term.Prefilter.Pass(v) {
+		// value can't be matched by regexp/glob
+		return term.Op == TaggedTermNe || term.Op == TaggedTermNotMatch
+	}
+	return term.match(v)
+}
+
+// match check value without prefilter
+func (term *TaggedTerm) match(v string) bool {
 	switch term.Op {
 	case TaggedTermEq:
 		if term.HasWildcard {
-			return term.Glob.Match(v)
+			return term.matchGlob(v)
 		} else {
 			return v == term.Value
 		}
 	case TaggedTermNe:
 		if term.HasWildcard {
-			return !term.Glob.Match(v)
+			return !term.matchGlob(v)
 		} else {
 			return !(v == term.Value)
 		}
@@ -93,15 +191,45 @@ func (term *TaggedTerm) Match(v string) bool {
 		return term.Re.MatchString(v)
 	case TaggedTermNotMatch:
 		return !term.Re.MatchString(v)
+	case TaggedTermGt, TaggedTermGe, TaggedTermLt, TaggedTermLe:
+		// not numeric value is not matched
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return false
+		}
+		switch term.Op {
+		case TaggedTermGt:
+			return n > term.Number
+		case TaggedTermGe:
+			return n >= term.Number
+		case TaggedTermLt:
+			return n < term.Number
+		default:
+			return n <= term.Number
+		}
 	default:
 		// must be unreacheable
 		panic(fmt.Errorf("invalid op : %d", term.Op))
 	}
 }
 
+func (term *TaggedTerm) matchGlob(v string) bool {
+	if term.GGlob != nil {
+		return term.GGlob.Match(v)
+	}
+	return term.Glob.Match(v)
+}
+
 // TaggedTermList is parsed seriesByTag expression
 type TaggedTermList []TaggedTerm
 
+// sort terms by key (__name__ is first), op and value
+func (terms TaggedTermList) sort() {
+	sort.Slice(terms, func(i, j int) bool {
+		return termLess(&terms[i], &terms[j])
+	})
+}
+
 func (t TaggedTermList) WriteString(buf *strings.Builder) {
 	buf.WriteString("seriesByTag(")
 	for i := 0; i < len(t); i++ {
@@ -109,7 +237,9 @@ func (t TaggedTermList) WriteString(buf *strings.Builder) {
 			buf.WriteByte(',')
 		}
 		buf.WriteByte('\'')
-		t[i].WriteString(buf)
+		writeEscaped(buf, t[i].Key)
+		buf.WriteString(t[i].Op.String())
+		writeEscaped(buf, t[i].Value)
 		buf.WriteByte('\'')
 	}
 	buf.WriteString(")")
@@ -122,48 +252,54 @@ func (t TaggedTermList) String() string {
 	return buf.String()
 }
 
-// MatchByPath match against tags map
+// MatchByTagsMap match against tags map (absent tag is an empty value, like in graphite)
 func (terms TaggedTermList) MatchByTagsMap(tags map[string]string) bool {
-	for _, term := range terms {
-		if v, ok := tags[term.Key]; ok {
-			if !term.Match(v) {
+	return terms.MatchBySource(TagMapSource(tags))
+}
+
+// MatchBySource match against tags source (absent tag is an empty value, like in graphite)
+func (terms TaggedTermList) MatchBySource(src TagSource) bool {
+	if sorted, ok := src.(SortedTagSource); ok {
+		return terms.MatchByTags(sorted.SortedTags())
+	}
+	for i := range terms {
+		if terms[i].IsWildcardKey() {
+			if !terms[i].MatchKeySource(src) {
 				return false
 			}
-		} else if term.Op != TaggedTermNe && term.Op != TaggedTermNotMatch {
-			// keys with != or ~=! check can be not exist, but others not
+			continue
+		}
+		v, _ := src.Get(terms[i].Key)
+		if !terms[i].Match(v) {
 			return false
 		}
 	}
 	return true
 }
 
-// MatchByPath match against tags slice
+// MatchByTags match against sorted tags slice (absent tag is an empty value, like in graphite)
 func (terms TaggedTermList) MatchByTags(tags []Tag) bool {
 	var i int
-LOOP:
-	for _, term := range terms {
-		if len(tags) == i {
-			if term.Op == TaggedTermNe || term.Op == TaggedTermNotMatch {
-				// != and ~=! can be skiped and key can not exist
-				continue LOOP
+	for n := range terms {
+		term := &terms[n]
+		if term.IsWildcardKey() {
+			if !term.MatchKeyTags(tags) {
+				return false
 			}
-			return false
+			continue
 		}
-		if term.Key != tags[i].Key {
-			// scan for tag
-			for ; i < len(tags); i++ {
-				if tags[i].Key == term.Key {
-					break
-				}
-			}
-			if len(tags) == i {
-				if term.Op == TaggedTermNe || term.Op == TaggedTermNotMatch {
-					// != and ~=! can be skiped and key can not exist
-					continue LOOP
-				}
+		// scan for tag, terms and tags are sorted
+		j := i
+		for j < len(tags) && tags[j].Key != term.Key {
+			j++
+		}
+		if j == len(tags) {
+			if !term.MatchEmpty() {
 				return false
 			}
+			continue
 		}
+		i = j
 		if !term.Match(tags[i].Value) {
 			return false
 		}
@@ -171,79 +307,32 @@ LOOP:
 	return true
 }
 
-func parseString(s string) (string, string, error) {
-	if s[0] != '\'' && s[0] != '"' {
-		return "", "", ErrNodeNotTerminated{s}
-	}
-
-	match := s[0]
-
-	s = s[1:]
-
-	var i int
-	for i < len(s) && s[i] != match {
-		i++
-	}
-
-	if i == len(s) {
-		return "", "", ErrNodeNotTerminated{s}
-	}
-
-	return s[:i], s[i+1:], nil
+func ParseSeriesByTag(query string) (terms TaggedTermList, err error) {
+	return ParseSeriesByTagDialect(query, DialectGraphiteClickHouse)
 }
 
-func seriesByTagArgs(query string, args []string) (n int, err error) {
-	// trim spaces
-	e := strings.Trim(query, " ")
-	if !strings.HasPrefix(e, "seriesByTag(") {
-		err = ErrQueryInvalid{query}
-		return
-	}
-	if e[len(e)-1] != ')' {
-		err = ErrQueryInvalid{query}
+// ParseSeriesByTagDialect parse seriesByTag query with dialect semantics
+func ParseSeriesByTagDialect(query string, dialect Dialect) (terms TaggedTermList, err error) {
+	var conditions []string
+	if conditions, err = SeriesByTagArgs(query); err != nil {
 		return
 	}
-	e = e[12 : len(e)-1]
 
-	for len(e) > 0 {
-		var arg string
-		if e[0] == '\'' || e[0] == '"' {
-			if arg, e, err = parseString(e); err != nil {
-				return
-			}
-			// skip empty arg
-			if arg != "" {
-				if n == len(args) {
-					err = ErrExprOverflow{query}
-					return
-				}
-				args[n] = arg
-				n++
-			}
-		} else if e[0] == ' ' || e[0] == ',' {
-			e = e[1:]
-		} else {
-			err = ErrNodeNotTerminated{e}
-			return
-		}
-	}
-	return
+	return ParseTaggedConditionsDialect(conditions, dialect)
 }
 
-func ParseSeriesByTag(query string) (terms TaggedTermList, err error) {
-	var (
-		n          int
-		conditions [128]string
-	)
-	n, err = seriesByTagArgs(query, conditions[:])
-	if err != nil {
-		return
-	}
+func ParseTaggedConditions(conditions []string) (terms TaggedTermList, err error) {
+	return ParseTaggedConditionsDialect(conditions, DialectGraphiteClickHouse)
+}
 
-	return ParseTaggedConditions(conditions[:n])
+// ParseTaggedConditionsDialect parse seriesByTag conditions with dialect semantics
+// (=~ and !=~ regexps are rewrited with dialect anchors, normalized query must be parsed with the same dialect)
+func ParseTaggedConditionsDialect(conditions []string, dialect Dialect) (terms TaggedTermList, err error) {
+	return parseTaggedConditions(conditions, dialect, nil)
 }
 
-func ParseTaggedConditions(conditions []string) (terms TaggedTermList, err error) {
+// parseTaggedConditions parse seriesByTag conditions, already compiled terms are copied from interned terms (if not nil)
+func parseTaggedConditions(conditions []string, dialect Dialect, interned map[string]*TaggedTerm) (terms TaggedTermList, err error) {
 	if len(conditions) == 0 {
 		return
 	}
@@ -252,13 +341,37 @@ func ParseTaggedConditions(conditions []string) (terms TaggedTermList, err error
 	for i := 0; i < len(conditions); i++ {
 		s := conditions[i]
 
-		pos := strings.IndexAny(s, "=~!")
+		var pos int
+		if strings.HasPrefix(s, "~") {
+			// regexp key
+			if pos = strings.IndexAny(s[1:], "=!<>"); pos != -1 {
+				pos++
+			}
+		} else {
+			pos = strings.IndexAny(s, "=~!<>")
+		}
 		if pos < 1 {
 			return nil, ErrExprInvalid{s}
 		}
 		terms[i].Key = strings.TrimSpace(s[:pos])
 		s = s[pos:]
-		if strings.HasPrefix(s, "=") {
+		if s[0] == '>' || s[0] == '<' {
+			if strings.HasPrefix(s[1:], "=") {
+				if s[0] == '>' {
+					terms[i].Op = TaggedTermGe
+				} else {
+					terms[i].Op = TaggedTermLe
+				}
+				terms[i].Value = strings.TrimSpace(s[2:])
+			} else {
+				if s[0] == '>' {
+					terms[i].Op = TaggedTermGt
+				} else {
+					terms[i].Op = TaggedTermLt
+				}
+				terms[i].Value = strings.TrimSpace(s[1:])
+			}
+		} else if strings.HasPrefix(s, "=") {
 			if strings.HasPrefix(s, "=~") {
 				terms[i].Op = TaggedTermMatch
 				terms[i].Value = strings.TrimSpace(s[2:])
@@ -283,27 +396,36 @@ func ParseTaggedConditions(conditions []string) (terms TaggedTermList, err error
 			terms[i].Key = "__name__"
 		}
 
-		if err = terms[i].build(); err != nil {
-			return
-		}
-	}
-
-	sort.Slice(terms, func(i, j int) bool {
-		if terms[i].Key == terms[j].Key {
-			if terms[i].Op == terms[j].Op {
-				return terms[i].Value < terms[j].Value
-			} else {
-				return terms[i].Op < terms[j].Op
+		if interned != nil {
+			value := terms[i].Value
+			if terms[i].Op == TaggedTermMatch || terms[i].Op == TaggedTermNotMatch {
+				value = dialect.anchorRegexp(value)
+			}
+			key := terms[i].Key
+			if strings.HasPrefix(key, "~") {
+				key = "~" + dialect.anchorRegexp(key[1:])
+			}
+			kind := ""
+			if (terms[i].Op == TaggedTermEq || terms[i].Op == TaggedTermNe) && items.HasWildcard(value) {
+				if dialect&DialectLiteralEq != 0 {
+					kind = termKindLiteral
+				} else if terms[i].Key == "__name__" && dialect&DialectNameGGlob != 0 {
+					kind = termKindGGlob
+				}
+			}
+			if term, ok := interned[key+terms[i].Op.String()+value+kind]; ok {
+				// already compiled
+				terms[i] = *term
+				continue
 			}
 		}
 
-		if terms[i].Key == "__name__" {
-			return true
-		} else if terms[j].Key == "__name__" {
-			return false
+		if err = terms[i].build(dialect); err != nil {
+			return
 		}
-		return terms[i].Key < terms[j].Key
-	})
+	}
+
+	terms.sort()
 
 	return terms, nil
 }
@@ -423,6 +545,48 @@ func PathTags(path string) (tags []Tag, err error) {
 	return
 }
 
+// pathTagsB split tagged path (name, delimited with nameDelim, and tags, delimited with tagDelim) into sorted Tag's slice,
+// tags are appended to tags[:0]
+func pathTagsB(path string, nameDelim, tagDelim byte, tags []Tag) ([]Tag, error) {
+	tags = tags[:0]
+	pos := strings.IndexByte(path, nameDelim)
+	if pos < 1 || strings.IndexByte(path[:pos], '=') != -1 {
+		return tags, ErrPathInvalid{"name", "not found in " + path}
+	}
+	// unescape allocate only for escaped strings
+	tags = append(tags, Tag{Key: "__name__", Value: escape.Unescape(path[:pos])})
+	for args := path[pos+1:]; args != ""; {
+		kv := args
+		if end := strings.IndexByte(args, tagDelim); end == -1 {
+			args = ""
+		} else {
+			kv = args[:end]
+			args = args[end+1:]
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return tags, ErrPathInvalid{kv, "not delimited with ="}
+		}
+		tags = append(tags, Tag{Key: escape.Unescape(k), Value: escape.Unescape(v)})
+	}
+	// GTagsTree.MatchByTags require sorted tags
+	SortTags(tags)
+
+	return tags, nil
+}
+
+// PathTagsB split GraphiteMergeTree path format (like name?a=v1&b=v2&c=v3) into sorted Tag's slice, tags are appended to tags[:0].
+// Without allocations, if tags capacity is enough and path is not escaped.
+func PathTagsB(path string, tags []Tag) ([]Tag, error) {
+	return pathTagsB(path, '?', '&', tags)
+}
+
+// GraphitePathTagsB split Graphite tagged path format (like name;a=v1;b=v2;c=v3) into sorted Tag's slice, tags are appended to tags[:0].
+// Without allocations, if tags capacity is enough and path is not escaped.
+func GraphitePathTagsB(path string, tags []Tag) ([]Tag, error) {
+	return pathTagsB(path, ';', ';', tags)
+}
+
 // PathTagsMap split GraphiteMergeTree path format (like name?a=v1&b=v2&c=v3) into Tag's map
 func PathTagsMap(path string) (tags map[string]string, err error) {
 	name, args, ok := strings.Cut(path, "?")
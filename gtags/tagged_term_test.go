@@ -148,6 +148,28 @@ func TestPathTags(t *testing.T) {
 				}
 			}
 
+			gotTags, err = GraphitePathTagsB(path, gotTags)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GraphitePathTagsB() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil {
+				if !reflect.DeepEqual(gotTags, tt.wantTags) {
+					t.Errorf("GraphitePathTagsB() = %s", cmp.Diff(tt.wantTags, gotTags))
+				}
+			}
+
+			gotTags, err = PathTagsB(tt.path, gotTags)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PathTagsB() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil {
+				if !reflect.DeepEqual(gotTags, tt.wantTags) {
+					t.Errorf("PathTagsB() = %s", cmp.Diff(tt.wantTags, gotTags))
+				}
+			}
+
 			gotTagsMap = make(map[string]string)
 			err = GraphitePathTagsMapB(path, gotTagsMap)
 			if (err != nil) != tt.wantErr {
@@ -168,6 +190,49 @@ var (
 	pathTags         = "kube_pod_status_phase?app_kubernetes_io_component=metrics&app_kubernetes_io_name=kube-state-metrics&app_kubernetes_io_part_of=kube-state-metrics&app_kubernetes_io_version=2.7.0&helm_sh_chart=kube-state-metrics-4.24.0&instance=192.168.0.85%3A8080&job=kubernetes-service-endpoints"
 )
 
+func TestPathTagsB(t *testing.T) {
+	tests := []struct {
+		path     string // Graphite MergeTree path
+		wantTags []Tag
+		wantErr  error
+	}{
+		{
+			// unsorted
+			path:     "a?c=v3&b=v%3D2&a+b=v1",
+			wantTags: []Tag{{"__name__", "a"}, {"a b", "v1"}, {"b", "v=2"}, {"c", "v3"}},
+		},
+		{
+			path:     "a%3Fb?c=",
+			wantTags: []Tag{{"__name__", "a?b"}, {"c", ""}},
+		},
+		{path: "a", wantErr: ErrPathInvalid{"name", "not found in a"}},
+		{path: "?a=b", wantErr: ErrPathInvalid{"name", "not found in ?a=b"}},
+		{path: "a=b?c=d", wantErr: ErrPathInvalid{"name", "not found in a=b?c=d"}},
+		{path: "a?b=c&d", wantErr: ErrPathInvalid{"d", "not delimited with ="}},
+		{path: "a?b=c&=d", wantErr: ErrPathInvalid{"=d", "not delimited with ="}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			tags := make([]Tag, 0, 2)
+			gotTags, err := PathTagsB(tt.path, tags)
+			assert.Equal(t, tt.wantErr, err)
+			if err == nil {
+				assert.Equal(t, tt.wantTags, gotTags)
+			}
+
+			path := strings.Replace(tt.path, "?", ";", 1)
+			path = strings.ReplaceAll(path, "&", ";")
+			gotTags, err = GraphitePathTagsB(path, gotTags)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantTags, gotTags)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
 func BenchmarkPathTagsMap(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_, err := PathTagsMap(pathTags)
@@ -203,3 +268,25 @@ func BenchmarkPathTags_NoEscape(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkPathTagsB(b *testing.B) {
+	tags := make([]Tag, 0, 8)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if tags, err = PathTagsB(pathTags, tags); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPathTagsB_NoEscape(b *testing.B) {
+	tags := make([]Tag, 0, 8)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if tags, err = PathTagsB(pathTagsNoEscape, tags); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
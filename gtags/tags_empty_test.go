@@ -0,0 +1,109 @@
+package gtags
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/msaf1980/go-matcher/pkg/items"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGTagsTree_EmptyValue(t *testing.T) {
+	queries := []string{
+		"seriesByTag('name=a', 'b=')",          // 0: b is absent
+		"seriesByTag('name=a', 'b!=')",         // 1: b is present
+		"seriesByTag('name=a', 'b=~^$')",       // 2: b is absent
+		"seriesByTag('name=a', 'b=~^c|^$')",    // 3: b=c* or absent
+		"seriesByTag('name=a', 'b!=~^$')",      // 4: b is present
+		"seriesByTag('name=a', 'b!=~^c*$')",    // 5: b is present and not c* (regexp match empty)
+		"seriesByTag('name=a', 'b!=~^d')",      // 6: b not d* or absent
+		"seriesByTag('name=a', 'b=c*', 'd=')",  // 7: b=c* and d is absent
+		"seriesByTag('name=a', 'b!=c', 'd!=')", // 8: b!=c (or absent) and d is present
+		"seriesByTag('name=a', 'b=*')",         // 9: b is present (glob * don't match empty)
+	}
+	tests := []struct {
+		path string
+		want []int
+	}{
+		{path: "a?c=d", want: []int{0, 2, 3, 6}},
+		{path: "a?b=c", want: []int{1, 3, 4, 6, 7, 9}},
+		{path: "a?b=ca&d=e", want: []int{1, 3, 4, 5, 6, 8, 9}},
+		{path: "a?b=d", want: []int{1, 4, 5, 9}},
+		{path: "a?b=cd", want: []int{1, 3, 4, 5, 6, 7, 9}},
+		{path: "a?b=c&d=e", want: []int{1, 3, 4, 6, 9}},
+		{path: "a?d=e", want: []int{0, 2, 3, 6, 8}},
+	}
+
+	gtree := NewTree()
+	termsList := make([]TaggedTermList, len(queries))
+	for i, query := range queries {
+		var err error
+		if termsList[i], err = ParseSeriesByTag(query); err != nil {
+			t.Fatalf("ParseSeriesByTag(%q) error = %v", query, err)
+		}
+		if _, _, err = gtree.Add(query, i); err != nil {
+			t.Fatalf("GTagsTree.Add(%q) error = %v", query, err)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			tags, err := PathTags(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tagsMap := TagsMap(tags)
+
+			var got, gotMap []int
+			for i, terms := range termsList {
+				if terms.MatchByTags(tags) {
+					got = append(got, i)
+				}
+				if terms.MatchByTagsMap(tagsMap) {
+					gotMap = append(gotMap, i)
+				}
+			}
+			assert.Equal(t, tt.want, got, "TaggedTermList.MatchByTags")
+			assert.Equal(t, tt.want, gotMap, "TaggedTermList.MatchByTagsMap")
+
+			var store items.IndexStore
+			gtree.MatchByTags(tags, &store)
+			sort.Ints(store.N)
+			assert.Equal(t, tt.want, store.N, "GTagsTree.MatchByTags")
+
+			store.N = nil
+			gtree.MatchByTagsMap(tagsMap, &store)
+			sort.Ints(store.N)
+			assert.Equal(t, tt.want, store.N, "GTagsTree.MatchByTagsMap")
+		})
+	}
+}
+
+func TestTaggedTerm_MatchEmpty(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "seriesByTag('a=')", want: true},
+		{query: "seriesByTag('a=b')", want: false},
+		{query: "seriesByTag('a!=')", want: false},
+		{query: "seriesByTag('a!=b')", want: true},
+		{query: "seriesByTag('a=~b')", want: false},
+		{query: "seriesByTag('a=~b*')", want: true},
+		{query: "seriesByTag('a!=~b*')", want: false},
+		{query: "seriesByTag('a!=~b')", want: true},
+		{query: "seriesByTag('a=*')", want: false},
+		{query: "seriesByTag('a=b*')", want: false},
+		{query: "seriesByTag('a!=b*')", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			terms, err := ParseSeriesByTag(tt.query)
+			if err != nil {
+				t.Fatalf("ParseSeriesByTag(%q) error = %v", tt.query, err)
+			}
+			assert.Equal(t, tt.want, terms[0].MatchEmpty())
+			assert.Equal(t, tt.want, terms[0].Match(""))
+		})
+	}
+}
@@ -22,12 +22,12 @@ func TestTaggedTermList_Equal(t *testing.T) {
 			query:   "seriesByTag(' ')",
 			wantErr: true,
 		},
-		// empty
 		{
-			query:      "seriesByTag('')",
-			wantQuery:  "seriesByTag()",
-			matchPaths: []string{"a?a=v1&b=c", "a?b=c", "a?a=v1&b=c&e=v3"},
+			// empty argument is not a tag expression (like in graphite)
+			query:   "seriesByTag('')",
+			wantErr: true,
 		},
+		// empty
 		{
 			query:      "seriesByTag()",
 			wantQuery:  "seriesByTag()",
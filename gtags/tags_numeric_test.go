@@ -0,0 +1,130 @@
+package gtags
+
+import (
+	"testing"
+
+	"github.com/msaf1980/go-matcher/pkg/items"
+)
+
+func TestTaggedTermListNumeric(t *testing.T) {
+	tests := []testTaggedTermList{
+		{
+			query:     "seriesByTag('name=x', 'shard>=10', 'priority<5')",
+			wantQuery: "seriesByTag('__name__=x','priority<5','shard>=10')",
+			want: TaggedTermList{
+				{Key: "__name__", Op: TaggedTermEq, Value: "x"},
+				{Key: "priority", Op: TaggedTermLt, Value: "5", Number: 5},
+				{Key: "shard", Op: TaggedTermGe, Value: "10", Number: 10},
+			},
+			matchPaths: []string{"x?priority=4&shard=10", "x?priority=-1.5&shard=1e2", "x?a=b&priority=0&shard=10.5"},
+			missPaths: []string{
+				"x?priority=5&shard=10", "x?priority=4&shard=9.99", "x?shard=10", "x?priority=4",
+				"x?priority=a&shard=10", "x?priority=4&shard=", "y?priority=4&shard=10",
+			},
+		},
+		{
+			query:     "seriesByTag('name=x', 'a > 1.5', 'a<= 3')",
+			wantQuery: "seriesByTag('__name__=x','a>1.5','a<=3')",
+			want: TaggedTermList{
+				{Key: "__name__", Op: TaggedTermEq, Value: "x"},
+				{Key: "a", Op: TaggedTermGt, Value: "1.5", Number: 1.5},
+				{Key: "a", Op: TaggedTermLe, Value: "3", Number: 3},
+			},
+			matchPaths: []string{"x?a=2", "x?a=3", "x?a=1.51"},
+			missPaths:  []string{"x?a=1.5", "x?a=3.01", "x?a=NaN", "x?a=2a"},
+		},
+		// invalid numbers
+		{query: "seriesByTag('name=x', 'a>b')", wantErr: true},
+		{query: "seriesByTag('name=x', 'a>')", wantErr: true},
+		{query: "seriesByTag('name=x', 'a<=NaN')", wantErr: true},
+		{query: "seriesByTag('name=x', '>1')", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			runTestTaggedTermList(t, tt)
+		})
+	}
+}
+
+func TestGTagsTree_Numeric(t *testing.T) {
+	tests := []testGTagsTree{
+		{
+			queries: []string{
+				"seriesByTag('name=x', 'shard>=10', 'priority<5')",
+				"seriesByTag('name=x', 'shard>=10')",
+			},
+			want: &gTagsTreeStr{
+				Root: &taggedItemStr{
+					Items: []taggedItemsStr{
+						{
+							Key: "__name__",
+							Matched: []*taggedItemStr{
+								{
+									Term: "__name__=x",
+									Items: []taggedItemsStr{
+										{
+											Key: "priority",
+											Matched: []*taggedItemStr{
+												{
+													Term: "priority<5", Items: []taggedItemsStr{
+														{
+															Key: "shard",
+															Matched: []*taggedItemStr{
+																{
+																	Term: "shard>=10",
+																	Terminated: items.Terminated{
+																		Terminate: true,
+																		Query:     "seriesByTag('__name__=x','priority<5','shard>=10')",
+																	},
+																},
+															},
+														},
+													},
+												},
+											},
+										},
+										{
+											Key: "shard",
+											Matched: []*taggedItemStr{
+												{
+													Term: "shard>=10",
+													Terminated: items.Terminated{
+														Terminate: true,
+														Query:     "seriesByTag('__name__=x','shard>=10')",
+														Index:     1,
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				Queries: map[string]int{
+					"seriesByTag('name=x', 'shard>=10', 'priority<5')":   0,
+					"seriesByTag('__name__=x','priority<5','shard>=10')": 0,
+					"seriesByTag('name=x', 'shard>=10')":                 1,
+					"seriesByTag('__name__=x','shard>=10')":              1,
+				},
+				QueryIndex: map[int]string{
+					0: "seriesByTag('__name__=x','priority<5','shard>=10')",
+					1: "seriesByTag('__name__=x','shard>=10')",
+				},
+			},
+			match: map[string][]string{
+				"x?priority=4&shard=10": {
+					"seriesByTag('__name__=x','priority<5','shard>=10')", "seriesByTag('__name__=x','shard>=10')",
+				},
+				"x?priority=5&shard=12": {"seriesByTag('__name__=x','shard>=10')"},
+				"x?shard=1e3":           {"seriesByTag('__name__=x','shard>=10')"},
+
+				"x?priority=4&shard=9": {}, "x?priority=4&shard=a": {}, "x?priority=4": {},
+			},
+		},
+	}
+	for n, tt := range tests {
+		runTestGTagsTree(t, n, tt)
+	}
+}
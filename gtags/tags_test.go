@@ -6,15 +6,20 @@ import (
 	"strings"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 var (
-	cmpTransform = cmp.Transformer("Re", func(in *regexp.Regexp) string {
-		if in == nil {
-			return "<nil>"
-		}
-		return in.String()
-	})
+	cmpTransform = cmp.Options{
+		cmp.Transformer("Re", func(in *regexp.Regexp) string {
+			if in == nil {
+				return "<nil>"
+			}
+			return in.String()
+		}),
+		cmpopts.IgnoreFields(TaggedTerm{}, "Prefilter"),
+		cmpopts.IgnoreUnexported(TaggedTerm{}),
+	}
 )
 
 func storedTagsList(paths []string) (list [][]Tag) {
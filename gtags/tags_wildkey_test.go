@@ -0,0 +1,233 @@
+package gtags
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/msaf1980/go-matcher/glob"
+	"github.com/msaf1980/go-matcher/pkg/items"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaggedTermList_WildcardKey(t *testing.T) {
+	tests := []testTaggedTermList{
+		{
+			query:     "seriesByTag('name=x', 'k8s_*=prod')",
+			wantQuery: "seriesByTag('__name__=x','k8s_*=prod')",
+			want: TaggedTermList{
+				{Key: "__name__", Op: TaggedTermEq, Value: "x"},
+				{
+					Key: "k8s_*", Op: TaggedTermEq, Value: "prod",
+					KeyGlob: &glob.Glob{
+						Glob: "k8s_*", Node: "k8s_*", MinLen: 4, MaxLen: -1, Prefix: "k8s_",
+						Items: []items.Item{items.Star(0)},
+					},
+				},
+			},
+			matchPaths: []string{"x?k8s_ns=prod", "x?a=b&k8s_env=dev&k8s_ns=prod", "x?k8s_=prod&z=1"},
+			missPaths:  []string{"x?k8s_ns=dev", "x?k8s=prod", "x?a=prod", "x?a=b", "y?k8s_ns=prod"},
+		},
+		{
+			query:     "seriesByTag('name=x', '~^debug!=~.*')",
+			wantQuery: "seriesByTag('__name__=x','~^debug!=~.*')",
+			want: TaggedTermList{
+				{Key: "__name__", Op: TaggedTermEq, Value: "x"},
+				{
+					Key: "~^debug", Op: TaggedTermNotMatch, Value: ".*",
+					KeyRe: regexp.MustCompile("^debug"), Re: regexp.MustCompile(".*"),
+				},
+			},
+			// no key matches
+			matchPaths: []string{"x?a=b", "x?a=debug&b_debug=1"},
+			missPaths:  []string{"x?debug=1", "x?a=b&debug_level=2", "x?debugger=on&z=1"},
+		},
+		{
+			query:     "seriesByTag('name=x', '~^debug=~^(1|on)$', 'env!=prod')",
+			wantQuery: "seriesByTag('__name__=x','env!=prod','~^debug=~^(1|on)$')",
+			want: TaggedTermList{
+				{Key: "__name__", Op: TaggedTermEq, Value: "x"},
+				{Key: "env", Op: TaggedTermNe, Value: "prod"},
+				{
+					Key: "~^debug", Op: TaggedTermMatch, Value: "^(1|on)$",
+					KeyRe: regexp.MustCompile("^debug"), Re: regexp.MustCompile("^(1|on)$"),
+				},
+			},
+			// any key matches
+			matchPaths: []string{"x?debug=1", "x?debug=0&debug_sql=on", "x?debug_a=on&env=dev"},
+			missPaths:  []string{"x?debug=0", "x?debug=1&env=prod", "x?a=1"},
+		},
+		{
+			query:     "seriesByTag('name=x', 'k8s_*!=prod')",
+			wantQuery: "seriesByTag('__name__=x','k8s_*!=prod')",
+			want: TaggedTermList{
+				{Key: "__name__", Op: TaggedTermEq, Value: "x"},
+				{
+					Key: "k8s_*", Op: TaggedTermNe, Value: "prod",
+					KeyGlob: &glob.Glob{
+						Glob: "k8s_*", Node: "k8s_*", MinLen: 4, MaxLen: -1, Prefix: "k8s_",
+						Items: []items.Item{items.Star(0)},
+					},
+				},
+			},
+			matchPaths: []string{"x?k8s_ns=dev", "x?k8s_env=dev&k8s_ns=stage", "x?env=prod"},
+			missPaths:  []string{"x?k8s_ns=prod", "x?k8s_env=dev&k8s_ns=prod"},
+		},
+		{
+			query:     "seriesByTag('name=x', 'shard_*>10')",
+			wantQuery: "seriesByTag('__name__=x','shard_*>10')",
+			want: TaggedTermList{
+				{Key: "__name__", Op: TaggedTermEq, Value: "x"},
+				{
+					Key: "shard_*", Op: TaggedTermGt, Value: "10", Number: 10,
+					KeyGlob: &glob.Glob{
+						Glob: "shard_*", Node: "shard_*", MinLen: 6, MaxLen: -1, Prefix: "shard_",
+						Items: []items.Item{items.Star(0)},
+					},
+				},
+			},
+			matchPaths: []string{"x?shard_a=1&shard_b=11"},
+			missPaths:  []string{"x?shard_a=1&shard_b=10", "x?shard=11", "x?z=1"},
+		},
+		// invalid keys
+		{query: "seriesByTag('name=x', '~=a')", wantErr: true},
+		{query: "seriesByTag('name=x', '~(=a')", wantErr: true},
+		{query: "seriesByTag('name=x', 'k[=a')", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			runTestTaggedTermList(t, tt)
+		})
+	}
+}
+
+func TestGTagsTree_WildcardKey(t *testing.T) {
+	tests := []testGTagsTree{
+		{
+			queries: []string{
+				"seriesByTag('name=x', 'k8s_*=prod')",
+				"seriesByTag('name=x', '~^debug!=~.*')",
+				"seriesByTag('name=x', 'a=b', '~^debug!=~.*')",
+			},
+			want: &gTagsTreeStr{
+				Root: &taggedItemStr{
+					Items: []taggedItemsStr{
+						{
+							Key: "__name__",
+							Matched: []*taggedItemStr{
+								{
+									Term: "__name__=x",
+									Items: []taggedItemsStr{
+										{
+											Key: "a",
+											Matched: []*taggedItemStr{
+												{
+													Term: "a=b",
+													KeyItems: []*taggedItemStr{
+														{
+															Term: "~^debug!=~.*",
+															Terminated: items.Terminated{
+																Terminate: true,
+																Query:     "seriesByTag('__name__=x','a=b','~^debug!=~.*')",
+																Index:     2,
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+									KeyItems: []*taggedItemStr{
+										{
+											Term: "k8s_*=prod",
+											Terminated: items.Terminated{
+												Terminate: true,
+												Query:     "seriesByTag('__name__=x','k8s_*=prod')",
+											},
+										},
+										{
+											Term: "~^debug!=~.*",
+											Terminated: items.Terminated{
+												Terminate: true,
+												Query:     "seriesByTag('__name__=x','~^debug!=~.*')",
+												Index:     1,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				Queries: map[string]int{
+					"seriesByTag('name=x', 'k8s_*=prod')":            0,
+					"seriesByTag('name=x', '~^debug!=~.*')":          1,
+					"seriesByTag('name=x', 'a=b', '~^debug!=~.*')":   2,
+					"seriesByTag('__name__=x','k8s_*=prod')":         0,
+					"seriesByTag('__name__=x','~^debug!=~.*')":       1,
+					"seriesByTag('__name__=x','a=b','~^debug!=~.*')": 2,
+				},
+				QueryIndex: map[int]string{
+					0: "seriesByTag('__name__=x','k8s_*=prod')",
+					1: "seriesByTag('__name__=x','~^debug!=~.*')",
+					2: "seriesByTag('__name__=x','a=b','~^debug!=~.*')",
+				},
+			},
+			match: map[string][]string{
+				"x?k8s_ns=prod": {
+					"seriesByTag('__name__=x','k8s_*=prod')", "seriesByTag('__name__=x','~^debug!=~.*')",
+				},
+				"x?a=b&k8s_ns=prod": {
+					"seriesByTag('__name__=x','k8s_*=prod')", "seriesByTag('__name__=x','~^debug!=~.*')",
+					"seriesByTag('__name__=x','a=b','~^debug!=~.*')",
+				},
+				"x?a=b&debug_sql=1&k8s_ns=prod": {"seriesByTag('__name__=x','k8s_*=prod')"},
+				"x?a=b&debug=":                  {"seriesByTag('__name__=x','~^debug!=~.*')", "seriesByTag('__name__=x','a=b','~^debug!=~.*')"},
+				"y?k8s_ns=prod":                 {},
+			},
+		},
+	}
+	for n, tt := range tests {
+		runTestGTagsTree(t, n, tt)
+	}
+}
+
+func TestTaggedTerm_MatchKeySource(t *testing.T) {
+	terms, err := ParseSeriesByTag("seriesByTag('name=x', 'k8s_*=prod', '~^debug!=~.*')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{
+		"x;k8s_ns=prod":         true,
+		"x;a=b;k8s_ns=prod":     true,
+		"x;debug=1;k8s_ns=prod": false,
+		"x;k8s_ns=dev":          false,
+		"x;z=1":                 false,
+	} {
+		assert.Equal(t, want, terms.MatchBySource(GraphitePathSource(path)), path)
+		tags, err := GraphitePathTags(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, want, terms.MatchBySource(TagSliceSource(tags)), path)
+		assert.Equal(t, want, terms.MatchBySource(TagMapSource(TagsMap(tags))), path)
+	}
+
+	// source without keys iteration, no keys are matched
+	assert.False(t, terms[1].MatchKeySource(&labels{name: "x"}))
+	assert.True(t, terms[2].MatchKeySource(&labels{name: "x"}))
+}
+
+func TestValidate_WildcardKey(t *testing.T) {
+	for query, wantErr := range map[string]bool{
+		"seriesByTag('k8s_*=prod')":            false,
+		"seriesByTag('~^debug!=~.*')":          true,
+		"seriesByTag('k8s_*=prod', 'k8s_*=a')": false,
+	} {
+		terms, err := ParseSeriesByTag(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = terms.Validate()
+		assert.Equal(t, wantErr, err != nil, query)
+	}
+}
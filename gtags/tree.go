@@ -1,6 +1,8 @@
 package gtags
 
 import (
+	"sync"
+
 	"github.com/msaf1980/go-matcher/glob"
 	"github.com/msaf1980/go-matcher/pkg/items"
 )
@@ -13,13 +15,29 @@ type GTagsTree struct {
 	Root       *TaggedItem
 	Queries    map[string]int
 	QueryIndex map[int]string
+	Terms      map[string]*TaggedTerm // interned terms (regexp/glob is compiled once)
+
+	Dialect  Dialect      // queries parse dialect
+	Validate ValidateMode // queries semantic validation on Add (disabled by default)
+	Order    TermsOrder   // terms order on tree build (must be set before Add)
+
+	PrefilterStats bool // count prefilters checked and rejected values (must be set before Add, slow down parallel match)
+
+	memoPool sync.Pool // per match call terms results
 }
 
 func NewTree() *GTagsTree {
+	return NewTreeDialect(DialectGraphiteClickHouse)
+}
+
+// NewTreeDialect return tree, which parse queries with dialect semantics
+func NewTreeDialect(dialect Dialect) *GTagsTree {
 	return &GTagsTree{
 		Root:       new(TaggedItem),
 		Queries:    make(map[string]int),
 		QueryIndex: make(map[int]string),
+		Terms:      make(map[string]*TaggedTerm),
+		Dialect:    dialect,
 	}
 }
 
@@ -44,8 +62,14 @@ func (gtree *GTagsTree) Add(queryString string, index int) (normalized string, n
 		return
 	}
 
-	var terms TaggedTermList
-	if terms, err = ParseSeriesByTag(queryString); err != nil {
+	var (
+		conditions []string
+		terms      TaggedTermList
+	)
+	if conditions, err = SeriesByTagArgs(queryString); err != nil {
+		return
+	}
+	if terms, err = parseTaggedConditions(conditions, gtree.Dialect, gtree.Terms); err != nil {
 		return
 	}
 	normalized = terms.String()
@@ -55,19 +79,12 @@ func (gtree *GTagsTree) Add(queryString string, index int) (normalized string, n
 		err = glob.ErrGlobExist
 		return
 	}
-
-	if len(terms) == 0 {
-		gtree.Terminate = true
-		gtree.Query = normalized
-		gtree.Index = index
-
-	} else {
-		lastItem := gtree.Root.Parse(terms, normalized, index)
-		lastItem.Terminate = true
-		lastItem.Query = normalized
-		lastItem.Index = index
+	if err = gtree.validate(terms); err != nil {
+		return
 	}
 
+	gtree.add(terms, normalized, index)
+
 	gtree.Queries[queryString] = index
 	if normalized != queryString {
 		gtree.Queries[normalized] = index
@@ -95,34 +112,129 @@ func (gtree *GTagsTree) AddTerms(terms TaggedTermList, index int) (normalized st
 		err = glob.ErrGlobExist
 		return
 	}
-	if normalized, ok = gtree.QueryIndex[index]; ok {
+	if exist, ok := gtree.QueryIndex[index]; ok {
+		normalized = exist
 		err = glob.ErrIndexDup
 		return
 	}
+	if err = gtree.validate(terms); err != nil {
+		return
+	}
+
+	gtree.add(terms, normalized, index)
+
+	gtree.Queries[normalized] = index
+	gtree.QueryIndex[index] = normalized
+
+	n = index
+
+	return
+}
+
+// intern return interned term (identical terms are shared across queries)
+func (gtree *GTagsTree) intern(term *TaggedTerm) *TaggedTerm {
+	if gtree.Terms == nil {
+		gtree.Terms = make(map[string]*TaggedTerm)
+	}
+	key := term.String() + term.kind()
+	if interned, ok := gtree.Terms[key]; ok {
+		return interned
+	}
+	interned := new(TaggedTerm)
+	*interned = *term
+	interned.id = len(gtree.Terms) + 1
+	if gtree.PrefilterStats && interned.Prefilter != nil {
+		// don't modify prefilter of the source term
+		p := *interned.Prefilter
+		p.Count = true
+		interned.Prefilter = &p
+	}
+	gtree.Terms[key] = interned
+	return interned
+}
 
+// add add parsed query terms into tree
+func (gtree *GTagsTree) add(terms TaggedTermList, normalized string, index int) {
 	if len(terms) == 0 {
 		gtree.Terminated = items.Terminated{
 			Terminate: true, Index: index, Query: normalized,
 		}
-	} else {
-		gtree.Root.Parse(terms, normalized, index)
+		return
 	}
+	interned := make([]*TaggedTerm, len(terms))
+	for i := range terms {
+		interned[i] = gtree.intern(&terms[i])
+	}
+	orderTerms(interned, gtree.Order)
+	lastItem := gtree.Root.parse(interned, normalized, index)
+	lastItem.Terminate = true
+	lastItem.Query = normalized
+	lastItem.Index = index
+}
 
-	gtree.Queries[normalized] = index
-	gtree.QueryIndex[index] = normalized
-
-	n = index
+// getMemo return cleared per call terms results
+func (gtree *GTagsTree) getMemo() *termsMemo {
+	memo, _ := gtree.memoPool.Get().(*termsMemo)
+	if memo == nil {
+		memo = new(termsMemo)
+	}
+	memo.reset(len(gtree.Terms) + 1)
+	return memo
+}
 
+// matchRoot store root Terminated (query without terms)
+func (gtree *GTagsTree) matchRoot(store items.Store) (matched int) {
+	if gtree.Terminate {
+		store.Store(gtree.Terminated.Query, gtree.Terminated.Index)
+		matched++
+	}
 	return
 }
 
 func (gtree *GTagsTree) MatchByTagsMap(tags map[string]string, store items.Store) (matched int) {
-	return gtree.Root.MatchByTagsMap(tags, store)
+	matched = gtree.matchRoot(store)
+	memo := gtree.getMemo()
+	matched += gtree.Root.matchByTagsMap(tags, store, memo)
+	gtree.memoPool.Put(memo)
+	return
 }
 
 func (gtree *GTagsTree) MatchByTags(tags []Tag, store items.Store) (matched int) {
-	if gtree.Terminate {
-		store.Store(gtree.Terminated.Query, gtree.Terminated.Index)
+	matched = gtree.matchRoot(store)
+	memo := gtree.getMemo()
+	matched += gtree.Root.matchByTags(tags, store, memo)
+	gtree.memoPool.Put(memo)
+	return
+}
+
+// MatchBySource match tags from source (sorted tags are matched with MatchByTags)
+func (gtree *GTagsTree) MatchBySource(src TagSource, store items.Store) (matched int) {
+	if sorted, ok := src.(SortedTagSource); ok {
+		return gtree.MatchByTags(sorted.SortedTags(), store)
+	}
+	matched = gtree.matchRoot(store)
+	memo := gtree.getMemo()
+	matched += gtree.Root.matchBySource(src, store, memo)
+	gtree.memoPool.Put(memo)
+	return
+}
+
+// MatchByGraphitePath match Graphite tagged path (like name;a=v1;b=v2), tags is a caller-owned scratch buffer, reused between calls.
+// Without allocations, if tags capacity is enough and path is not escaped.
+func (gtree *GTagsTree) MatchByGraphitePath(path string, tags *[]Tag, store items.Store) (matched int, err error) {
+	if *tags, err = GraphitePathTagsB(path, *tags); err != nil {
+		return
 	}
-	return gtree.Root.MatchByTags(tags, store)
+	matched = gtree.MatchByTags(*tags, store)
+	return
+}
+
+// MatchByMergeTreePath match GraphiteMergeTree tagged path (like name?a=v1&b=v2), tags is a caller-owned scratch buffer, reused between calls.
+// Without allocations, if tags capacity is enough and path is not escaped.
+func (gtree *GTagsTree) MatchByMergeTreePath(path string, tags *[]Tag, store items.Store) (matched int, err error) {
+	if *tags, err = PathTagsB(path, *tags); err != nil {
+		return
+	}
+	matched = gtree.MatchByTags(*tags, store)
+	return
 }
@@ -0,0 +1,71 @@
+package gtags
+
+import (
+	"github.com/msaf1980/go-matcher/pkg/items"
+)
+
+// MatchBatch match Graphite tagged paths (like name;a=v1;b=v2) in parallel with workers goroutines (workers < 1 - use GOMAXPROCS)
+// and return matched queries indexes for each path (nil if path not matched or invalid).
+//
+// Tree must not be modified during match.
+func (gtree *GTagsTree) MatchBatch(paths []string, workers int) (matched [][]int) {
+	matched = make([][]int, len(paths))
+	gtree.MatchBatchFunc(paths, workers, func(n int, index []int) {
+		if len(index) > 0 {
+			matched[n] = append([]int(nil), index...)
+		}
+	})
+	return
+}
+
+// MatchBatchFunc match Graphite tagged paths (like name;a=v1;b=v2) in parallel with workers goroutines (workers < 1 - use GOMAXPROCS)
+// and stream matched queries indexes for each path through f (n is a path position in paths).
+// Invalid paths are not matched.
+//
+// f called concurrently from workers and index slice reused after f return, so copy it if needed.
+// Tree must not be modified during match.
+func (gtree *GTagsTree) MatchBatchFunc(paths []string, workers int, f func(n int, index []int)) {
+	items.Batch(len(paths), workers, func(start, end int) {
+		var (
+			store items.IndexStore
+			tags  []Tag
+		)
+		store.Grow(4)
+		for i := start; i < end; i++ {
+			store.Init()
+			_, _ = gtree.MatchByGraphitePath(paths[i], &tags, &store)
+			f(i, store.N)
+		}
+	})
+}
+
+// MatchBatchByTags match tags in parallel with workers goroutines (workers < 1 - use GOMAXPROCS)
+// and return matched queries indexes for each tags set (nil if not matched).
+//
+// Tree must not be modified during match.
+func (gtree *GTagsTree) MatchBatchByTags(tags [][]Tag, workers int) (matched [][]int) {
+	matched = make([][]int, len(tags))
+	gtree.MatchBatchByTagsFunc(tags, workers, func(n int, index []int) {
+		if len(index) > 0 {
+			matched[n] = append([]int(nil), index...)
+		}
+	})
+	return
+}
+
+// MatchBatchByTagsFunc match tags in parallel with workers goroutines (workers < 1 - use GOMAXPROCS)
+// and stream matched queries indexes for each tags set through f (n is a position in tags).
+//
+// f called concurrently from workers and index slice reused after f return, so copy it if needed.
+// Tree must not be modified during match.
+func (gtree *GTagsTree) MatchBatchByTagsFunc(tags [][]Tag, workers int, f func(n int, index []int)) {
+	items.Batch(len(tags), workers, func(start, end int) {
+		var store items.IndexStore
+		store.Grow(4)
+		for i := start; i < end; i++ {
+			store.Init()
+			_ = gtree.MatchByTags(tags[i], &store)
+			f(i, store.N)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package gtags
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/msaf1980/go-matcher/pkg/items"
+)
+
+func TestGTagsTree_MatchBatch(t *testing.T) {
+	w := NewTree()
+	for j := 0; j < len(queriesBatchHugeMoira); j++ {
+		if _, _, err := w.Add(queriesBatchHugeMoira[j], j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := generateTaggedMetrics(termsBatchHugeMoira, len(termsBatchHugeMoira))
+	tagsList := tagsList(paths)
+
+	want := make([][]int, len(paths))
+	for i, tags := range tagsList {
+		var store items.IndexStore
+		if w.MatchByTags(tags, &store) > 0 {
+			want[i] = store.N
+		}
+	}
+
+	for _, workers := range []int{0, 1, 3} {
+		if got := w.MatchBatch(paths, workers); !reflect.DeepEqual(want, got) {
+			t.Errorf("GTagsTree.MatchBatch(%d) mismatch", workers)
+		}
+		if got := w.MatchBatchByTags(tagsList, workers); !reflect.DeepEqual(want, got) {
+			t.Errorf("GTagsTree.MatchBatchByTags(%d) mismatch", workers)
+		}
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/msaf1980/go-matcher/glob"
 	"github.com/msaf1980/go-matcher/pkg/items"
+	"github.com/stretchr/testify/assert"
 )
 
 type taggedItemsStr struct {
@@ -27,6 +29,17 @@ func StringTaggedItems(items TaggedItems) taggedItemsStr {
 			t.Matched[i] = StringTaggedItem(items.Matched[i])
 		}
 	}
+	if len(items.MatchedMap) > 0 {
+		// full match items are appended after wildcard items, sorted by value
+		values := make([]string, 0, len(items.MatchedMap))
+		for v := range items.MatchedMap {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		for _, v := range values {
+			t.Matched = append(t.Matched, StringTaggedItem(items.MatchedMap[v]))
+		}
+	}
 	if items.NotMatched != nil {
 		t.NotMatched = make([]*taggedItemStr, len(items.NotMatched))
 		for i := 0; i < len(t.NotMatched); i++ {
@@ -42,7 +55,8 @@ type taggedItemStr struct {
 
 	items.Terminated
 
-	Items []taggedItemsStr `json:"match"` // next possible parts slice
+	Items    []taggedItemsStr `json:"match"` // next possible parts slice
+	KeyItems []*taggedItemStr `json:"key_match"`
 }
 
 func StringTaggedItem(treeItem *TaggedItem) *taggedItemStr {
@@ -63,6 +77,9 @@ func StringTaggedItem(treeItem *TaggedItem) *taggedItemStr {
 			treeItemStr.Items = append(treeItemStr.Items, StringTaggedItems(childs))
 		}
 	}
+	for _, child := range treeItem.KeyItems {
+		treeItemStr.KeyItems = append(treeItemStr.KeyItems, StringTaggedItem(child))
+	}
 	return treeItemStr
 }
 
@@ -175,6 +192,8 @@ func verifyGTagsTree(t *testing.T, inGlobs []string, match map[string][]string,
 			}
 			store.Init()
 			matched = gtree.MatchByTagsMap(tagsMap, &store)
+			sort.Strings(store.S.S)
+			sort.Ints(store.Index.N)
 
 			if !reflect.DeepEqual(wantQueries, store.S.S) {
 				t.Fatalf("GTagsTree(%#v).MatchByTagsMap(%q) globs = %s", inGlobs, path, cmp.Diff(wantQueries, store.S.S))
@@ -200,3 +219,264 @@ func verifyGTagsTree(t *testing.T, inGlobs []string, match map[string][]string,
 		})
 	}
 }
+
+func TestGTagsTree_Intern(t *testing.T) {
+	gtree := NewTree()
+	gtree.PrefilterStats = true
+	queries := []string{
+		"seriesByTag('name=a', 'z=~^x.*y$')",
+		"seriesByTag('name=a', 'c=d', 'z=~^x.*y$')",
+		"seriesByTag('name=b', 'z=~^x.*y$', 'c=d*')",
+		"seriesByTag('name=b', 'c=d*')",
+	}
+	for i, query := range queries {
+		if _, _, err := gtree.Add(query, i); err != nil {
+			t.Fatalf("GTagsTree.Add(%q) error = %v", query, err)
+		}
+	}
+	// __name__=a, __name__=b, c=d, c=d*, z=~^x.*y$
+	assert.Equal(t, 5, len(gtree.Terms))
+
+	re := gtree.Terms["z=~^x.*y$"]
+	a := gtree.Root.Items[0].MatchedMap["a"]
+	b := gtree.Root.Items[0].MatchedMap["b"]
+	assert.Same(t, re, a.Items[1].Matched[0].Term)
+	assert.Same(t, re, a.Items[0].MatchedMap["d"].Items[0].Matched[0].Term)
+	assert.Same(t, re, b.Items[0].Matched[0].Items[0].Matched[0].Term)
+
+	// each distinct term is evaluated once per match call
+	for path, want := range map[string][]int{
+		"a?c=d&z=xzy": {0, 1},
+		"a?c=d&z=xz":  nil,
+		"b?c=d&z=xzy": {2, 3},
+	} {
+		tags, err := PathTags(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var store items.IndexStore
+		gtree.MatchByTags(tags, &store)
+		sort.Ints(store.N)
+		assert.Equal(t, want, store.N, path)
+
+		store.N = nil
+		gtree.MatchByTagsMap(TagsMap(tags), &store)
+		sort.Ints(store.N)
+		assert.Equal(t, want, store.N, path)
+	}
+	checked, _ := re.Prefilter.Stats()
+	assert.Equal(t, uint64(6), checked)
+	checked, _ = gtree.Terms["c=d*"].Prefilter.Stats()
+	assert.Equal(t, uint64(2), checked)
+}
+
+func TestGTagsTree_Intern_Kind(t *testing.T) {
+	gtree := NewTree()
+	query := "seriesByTag('name=a.*', 'b=c')"
+	if _, _, err := gtree.Add(query, 0); err != nil {
+		t.Fatalf("GTagsTree.Add(%q) error = %v", query, err)
+	}
+	// same term string, but dot-aware glob matcher
+	terms, err := ParseSeriesByTagDialect("seriesByTag('name=a.*', 'b=d')", DialectNameGGlob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = gtree.AddTerms(terms, 1); err != nil {
+		t.Fatalf("GTagsTree.AddTerms(%q) error = %v", terms.String(), err)
+	}
+	// __name__=a.* (glob), __name__=a.* (gglob), b=c, b=d
+	assert.Equal(t, 4, len(gtree.Terms))
+	assert.NotNil(t, gtree.Terms["__name__=a.*"].Glob)
+	assert.NotNil(t, gtree.Terms["__name__=a.*"+termKindGGlob].GGlob)
+
+	for path, want := range map[string][]int{
+		"a.x?b=c":   {0},
+		"a.x.y?b=c": {0},
+		"a.x?b=d":   {1},
+		"a.x.y?b=d": nil,
+	} {
+		tags, err := PathTags(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var store items.IndexStore
+		gtree.MatchByTags(tags, &store)
+		assert.Equal(t, want, store.N, path)
+	}
+}
+
+func TestGTagsTree_Match_EmptyTags(t *testing.T) {
+	queries := []string{
+		"seriesByTag('a!=b')",
+		"seriesByTag('a=')",
+		"seriesByTag('a=b')",
+		"seriesByTag('a=~c*')",
+		"seriesByTag('a!=~.+')",
+	}
+	want := []int{0, 1, 3, 4}
+	gtree := NewTree()
+	for i, query := range queries {
+		if _, _, err := gtree.Add(query, i); err != nil {
+			t.Fatalf("GTagsTree.Add(%q) error = %v", query, err)
+		}
+	}
+	for _, tags := range [][]Tag{nil, {}} {
+		var store items.IndexStore
+		gtree.MatchByTags(tags, &store)
+		sort.Ints(store.N)
+		assert.Equal(t, want, store.N, "MatchByTags")
+
+		store.N = nil
+		gtree.MatchByTagsMap(map[string]string{}, &store)
+		sort.Ints(store.N)
+		assert.Equal(t, want, store.N, "MatchByTagsMap")
+
+		store.N = nil
+		gtree.MatchBySource(TagMapSource(map[string]string{}), &store)
+		sort.Ints(store.N)
+		assert.Equal(t, want, store.N, "MatchBySource")
+
+		var details []int
+		for _, m := range gtree.MatchDetailsByTags(tags, nil) {
+			details = append(details, m.Index)
+		}
+		sort.Ints(details)
+		assert.Equal(t, want, details, "MatchDetailsByTags")
+	}
+	for i, query := range queries {
+		terms, err := ParseSeriesByTag(query)
+		if err != nil {
+			t.Fatalf("ParseSeriesByTag(%q) error = %v", query, err)
+		}
+		matched := i != 2
+		assert.Equal(t, matched, terms.MatchByTags(nil), query)
+		assert.Equal(t, matched, terms.MatchByTagsMap(map[string]string{}), query)
+		assert.Equal(t, matched, terms.MatchBySource(TagMapSource(map[string]string{})), query)
+		_, ok := terms.MatchDetailsByTags(nil)
+		assert.Equal(t, matched, ok, query)
+	}
+}
+
+func TestGTagsTree_AddTerms(t *testing.T) {
+	gtree := NewTree()
+	terms, err := ParseSeriesByTag("seriesByTag('name=a', 'b=~c')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	normalized, n, err := gtree.AddTerms(terms, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "seriesByTag('__name__=a','b=~c')", normalized)
+	assert.Equal(t, 1, n)
+
+	_, _, err = gtree.Add("seriesByTag('name=a', 'b=~c')", 2)
+	assert.Equal(t, glob.ErrGlobExist, err)
+
+	tags, err := PathTags("a?b=xcx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var store items.IndexStore
+	assert.Equal(t, 1, gtree.MatchByTags(tags, &store))
+	assert.Equal(t, []int{1}, store.N)
+}
+
+func TestGTagsTree_MatchByPath(t *testing.T) {
+	gtree := NewTree()
+	queries := []string{
+		"seriesByTag('name=a', 'b=c')",
+		"seriesByTag('name=a', 'b=~^c', 'd!=e')",
+		"seriesByTag('name=a b', 'c=d=e')",
+	}
+	for i, query := range queries {
+		if _, _, err := gtree.Add(query, i); err != nil {
+			t.Fatalf("GTagsTree.Add(%q) error = %v", query, err)
+		}
+	}
+	tests := []struct {
+		path    string // Graphite MergeTree path
+		want    []int
+		wantErr bool
+	}{
+		{path: "a?b=c", want: []int{0, 1}},
+		{path: "a?d=f&b=cd&a=1", want: []int{1}}, // unsorted
+		{path: "a?d=e&b=c", want: []int{0}},
+		{path: "a+b?c=d%3De", want: []int{2}},
+		{path: "a", want: []int{}, wantErr: true},
+	}
+	var (
+		store items.IndexStore
+		tags  []Tag
+	)
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			store.Init()
+			n, err := gtree.MatchByMergeTreePath(tt.path, &tags, &store)
+			assert.Equal(t, tt.wantErr, err != nil)
+			sort.Ints(store.N)
+			assert.Equal(t, tt.want, store.N)
+			assert.Equal(t, len(tt.want), n)
+
+			path := strings.Replace(tt.path, "?", ";", 1)
+			path = strings.ReplaceAll(path, "&", ";")
+			store.Init()
+			n, err = gtree.MatchByGraphitePath(path, &tags, &store)
+			assert.Equal(t, tt.wantErr, err != nil)
+			sort.Ints(store.N)
+			assert.Equal(t, tt.want, store.N)
+			assert.Equal(t, len(tt.want), n)
+		})
+	}
+}
+
+func TestGTagsTree_MatchByPath_Allocs(t *testing.T) {
+	gtree := NewTree()
+	for i, query := range []string{
+		"seriesByTag('name=kube_pod_status_phase', 'job=~^kubernetes')",
+		"seriesByTag('name=kube_pod_status_phase', 'instance=192.168.0.85_8080', 'app_kubernetes_io_version!=2.7.0')",
+	} {
+		if _, _, err := gtree.Add(query, i); err != nil {
+			t.Fatalf("GTagsTree.Add(%q) error = %v", query, err)
+		}
+	}
+	var store items.IndexStore
+	store.Grow(4)
+	tags := make([]Tag, 0, 8)
+	allocs := testing.AllocsPerRun(100, func() {
+		store.Init()
+		if _, err := gtree.MatchByMergeTreePath(pathTagsNoEscape, &tags, &store); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+	assert.Equal(t, []int{0}, store.N)
+
+	path := strings.Replace(pathTagsNoEscape, "?", ";", 1)
+	path = strings.ReplaceAll(path, "&", ";")
+	allocs = testing.AllocsPerRun(100, func() {
+		store.Init()
+		if _, err := gtree.MatchByGraphitePath(path, &tags, &store); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+	assert.Equal(t, []int{0}, store.N)
+}
+
+func BenchmarkGTagsTree_MatchByMergeTreePath(b *testing.B) {
+	gtree := NewTree()
+	if _, _, err := gtree.Add("seriesByTag('name=kube_pod_status_phase', 'job=~^kubernetes')", 0); err != nil {
+		b.Fatal(err)
+	}
+	var store items.IndexStore
+	store.Grow(4)
+	tags := make([]Tag, 0, 8)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		store.Init()
+		if _, err := gtree.MatchByMergeTreePath(pathTagsNoEscape, &tags, &store); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
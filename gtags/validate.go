@@ -0,0 +1,97 @@
+package gtags
+
+// ValidateMode is a queries semantic validation mode for GTagsTree.Add
+type ValidateMode int8
+
+const (
+	// ValidateNone disable queries validation
+	ValidateNone ValidateMode = iota
+	// ValidateErrors reject queries, which never matched or without terms, required non-empty value (warnings are ignored)
+	ValidateErrors
+	// ValidateStrict reject queries with validation warnings too
+	ValidateStrict
+)
+
+// literal check for literal (= without wildcards) term
+func (term *TaggedTerm) literal() bool {
+	return term.Op == TaggedTermEq && !term.HasWildcard
+}
+
+// positive check for term, which required key exist (=, =~ or numeric)
+func (term *TaggedTerm) positive() bool {
+	return term.Op == TaggedTermEq || term.Op == TaggedTermMatch || term.Op.IsNumeric()
+}
+
+// conflict check for terms (with the same key), which never matched together
+func (term *TaggedTerm) conflict(other *TaggedTerm) bool {
+	if term.IsWildcardKey() {
+		// different tags can be matched by wildcard key
+		return false
+	}
+	if term.literal() {
+		return !other.match(term.Value)
+	}
+	if other.literal() {
+		return !term.match(other.Value)
+	}
+	return false
+}
+
+// Validate check terms semantic. Return error for queries, which never matched (ErrTermsConflict, like a=x and a!=x)
+// or not allowed by graphite (ErrNegativeOnly, at least one term must required non-empty value)
+// and warnings for duplicate terms (ErrTermDuplicate) and positive regexp terms, matched empty value (ErrTermMatchEmpty).
+func (terms TaggedTermList) Validate() (warnings []error, err error) {
+	if len(terms) == 0 {
+		return
+	}
+	nonEmpty := false
+	for i := range terms {
+		term := &terms[i]
+		if term.IsWildcardKey() {
+			// positive wildcard key term required any matched key exist
+			if term.positive() {
+				nonEmpty = true
+			}
+		} else if !term.MatchEmpty() {
+			// tag!= is also required non-empty value
+			nonEmpty = true
+		} else if term.positive() && !term.literal() {
+			warnings = append(warnings, ErrTermMatchEmpty{term.String()})
+		}
+		dup := false
+		for j := i + 1; j < len(terms); j++ {
+			other := &terms[j]
+			if term.Key != other.Key {
+				continue
+			}
+			if term.Op == other.Op && term.Value == other.Value {
+				if !dup {
+					// warn once for each redundant term
+					dup = true
+					warnings = append(warnings, ErrTermDuplicate{term.String()})
+				}
+			} else if term.conflict(other) {
+				return warnings, ErrTermsConflict{term.String(), other.String()}
+			}
+		}
+	}
+	if !nonEmpty {
+		err = ErrNegativeOnly{terms.String()}
+	}
+	return
+}
+
+// validate check terms with tree validation mode
+func (gtree *GTagsTree) validate(terms TaggedTermList) error {
+	if gtree.Validate == ValidateNone {
+		return nil
+	}
+	warnings, err := terms.Validate()
+	if err != nil {
+		return err
+	}
+	if gtree.Validate == ValidateStrict && len(warnings) > 0 {
+		return warnings[0]
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package gtags
+
+import (
+	"testing"
+
+	"github.com/msaf1980/go-matcher/glob"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaggedTermList_Validate(t *testing.T) {
+	tests := []struct {
+		query        string
+		wantWarnings []error
+		wantErr      error
+	}{
+		{query: "seriesByTag('name=a', 'b=c', 'd!=e', 'f=~g')"},
+		{query: "seriesByTag('name=a*', 'b!=~c')"},
+		{query: "seriesByTag('a=b', 'a=~b', 'a!=c', 'a!=~^c', 'a=b*', 'a!=c*')"},
+		{query: "seriesByTag('a=~b', 'a=~c')"}, // can be matched by bc
+		{query: "seriesByTag('a=b*', 'a!=c*')"},
+		{query: "seriesByTag('a!=', 'b=')"}, // a is present
+		// contradictions
+		{
+			query:   "seriesByTag('name=a', 'b=c', 'b=d')",
+			wantErr: ErrTermsConflict{"b=c", "b=d"},
+		},
+		{
+			query:   "seriesByTag('name=a', 'b=c', 'b!=c')",
+			wantErr: ErrTermsConflict{"b=c", "b!=c"},
+		},
+		{
+			query:   "seriesByTag('name=a', 'b=c', 'b=~d')",
+			wantErr: ErrTermsConflict{"b=c", "b=~d"},
+		},
+		{
+			query:   "seriesByTag('name=a', 'b=c', 'b!=~^c$')",
+			wantErr: ErrTermsConflict{"b=c", "b!=~^c$"},
+		},
+		{
+			query:   "seriesByTag('name=a', 'b=c', 'b=d*')",
+			wantErr: ErrTermsConflict{"b=c", "b=d*"},
+		},
+		{
+			query:   "seriesByTag('name=a', 'b=c', 'b!=c*')",
+			wantErr: ErrTermsConflict{"b=c", "b!=c*"},
+		},
+		{
+			query:   "seriesByTag('name=a', 'b=', 'b!=')",
+			wantErr: ErrTermsConflict{"b=", "b!="},
+		},
+		{
+			query:   "seriesByTag('name=a', 'b=', 'b=~c')",
+			wantErr: ErrTermsConflict{"b=", "b=~c"},
+		},
+		// negative-only
+		{
+			query:   "seriesByTag('name!=a', 'b!=~c')",
+			wantErr: ErrNegativeOnly{"seriesByTag('__name__!=a','b!=~c')"},
+		},
+		{
+			query:   "seriesByTag('name=', 'b!=~^c')",
+			wantErr: ErrNegativeOnly{"seriesByTag('__name__=','b!=~^c')"},
+		},
+		{
+			query:        "seriesByTag('name=~.*', 'b!=c')",
+			wantWarnings: []error{ErrTermMatchEmpty{"__name__=~.*"}},
+			wantErr:      ErrNegativeOnly{"seriesByTag('__name__=~.*','b!=c')"},
+		},
+		// warnings
+		{
+			query:        "seriesByTag('name=a', 'b=c', 'b=c', 'b=c', 'name=a')",
+			wantWarnings: []error{ErrTermDuplicate{"__name__=a"}, ErrTermDuplicate{"b=c"}, ErrTermDuplicate{"b=c"}},
+		},
+		{
+			query:        "seriesByTag('name=a', 'b=~c*', 'd=*', 'e=~^$')",
+			wantWarnings: []error{ErrTermMatchEmpty{"b=~c*"}, ErrTermMatchEmpty{"e=~^$"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			terms, err := ParseSeriesByTag(tt.query)
+			if err != nil {
+				t.Fatalf("ParseSeriesByTag(%q) error = %v", tt.query, err)
+			}
+			warnings, err := terms.Validate()
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantWarnings, warnings)
+		})
+	}
+}
+
+func TestGTagsTree_Validate(t *testing.T) {
+	gtree := NewTree()
+	// disabled by default
+	_, _, err := gtree.Add("seriesByTag('name=a', 'b=c', 'b=d')", 0)
+	assert.NoError(t, err)
+
+	gtree = NewTree()
+	gtree.Validate = ValidateErrors
+	normalized, _, err := gtree.Add("seriesByTag('name=a', 'b=c', 'b=d')", 0)
+	assert.Equal(t, ErrTermsConflict{"b=c", "b=d"}, err)
+	assert.Equal(t, "seriesByTag('__name__=a','b=c','b=d')", normalized)
+	assert.Empty(t, gtree.Queries)
+	assert.Empty(t, gtree.Terms)
+
+	_, _, err = gtree.Add("seriesByTag('name!=a')", 0)
+	assert.Equal(t, ErrNegativeOnly{"seriesByTag('__name__!=a')"}, err)
+
+	terms, err := ParseSeriesByTag("seriesByTag('name!=a')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = gtree.AddTerms(terms, 0)
+	assert.Equal(t, ErrNegativeOnly{"seriesByTag('__name__!=a')"}, err)
+
+	// warnings are ignored
+	_, _, err = gtree.Add("seriesByTag('name=a', 'b=c', 'b=c')", 0)
+	assert.NoError(t, err)
+
+	gtree.Validate = ValidateStrict
+	_, _, err = gtree.Add("seriesByTag('name=a', 'b=~.*')", 1)
+	assert.Equal(t, ErrTermMatchEmpty{"b=~.*"}, err)
+	_, _, err = gtree.Add("seriesByTag('name=a', 'b=~.+')", 1)
+	assert.NoError(t, err)
+
+	_, _, err = gtree.Add("seriesByTag('name=a', 'b=~.+')", 2)
+	assert.Equal(t, glob.ErrGlobExist, err)
+}
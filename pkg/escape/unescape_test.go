@@ -1,6 +1,7 @@
 package escape
 
 import (
+	"bytes"
 	"strconv"
 	"strings"
 	"testing"
@@ -75,6 +76,8 @@ func Test_Query_Unescape(t *testing.T) {
 		{"a+b", "a%2Bb"},
 		{"a b", "a+b"},
 		{"a = b", "a+%3D+b"},
+		{"e+f", "e%2Bf"},
+		{"e+fg", "e%2Bfg"},
 	}
 
 	for i, tt := range tests {
@@ -83,6 +86,11 @@ func Test_Query_Unescape(t *testing.T) {
 			if tt.wantEscape != gotEscape {
 				t.Fatalf("Query(%q) = %q, want %q", tt.in, gotEscape, tt.wantEscape)
 			}
+			var buf bytes.Buffer
+			QueryTo(tt.in, &buf)
+			if tt.wantEscape != buf.String() {
+				t.Fatalf("QueryTo(%q) = %q, want %q", tt.in, buf.String(), tt.wantEscape)
+			}
 			gotUnescape := Unescape(gotEscape)
 			if tt.in != gotUnescape {
 				t.Fatalf("Unescape(%q) = %q, want %q", gotEscape, gotUnescape, tt.in)
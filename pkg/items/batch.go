@@ -0,0 +1,48 @@
+package items
+
+import (
+	"runtime"
+	"sync"
+)
+
+// batchWorkers return workers count for batch of n jobs (workers < 1 - use GOMAXPROCS)
+func batchWorkers(n, workers int) int {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+	return workers
+}
+
+// Batch split n jobs across workers goroutines and call f with [start, end) jobs range for each worker.
+// f is called concurrently, so it must use own (per-worker) state for store results.
+func Batch(n, workers int, f func(start, end int)) {
+	if n == 0 {
+		return
+	}
+	workers = batchWorkers(n, workers)
+	if workers == 1 {
+		f(0, n)
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	size := n / workers
+	rest := n % workers
+	start := 0
+	for i := 0; i < workers; i++ {
+		end := start + size
+		if i < rest {
+			end++
+		}
+		go func(start, end int) {
+			defer wg.Done()
+			f(start, end)
+		}(start, end)
+		start = end
+	}
+	wg.Wait()
+}
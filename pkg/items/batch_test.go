@@ -0,0 +1,36 @@
+package items
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	tests := []struct {
+		n       int
+		workers int
+	}{
+		{n: 0, workers: 0},
+		{n: 1, workers: 4},
+		{n: 7, workers: 1},
+		{n: 7, workers: 3},
+		{n: 100, workers: 8},
+		{n: 100, workers: 0},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.n)+"#"+strconv.Itoa(tt.workers), func(t *testing.T) {
+			visited := make([]int32, tt.n)
+			Batch(tt.n, tt.workers, func(start, end int) {
+				for i := start; i < end; i++ {
+					atomic.AddInt32(&visited[i], 1)
+				}
+			})
+			for i, v := range visited {
+				if v != 1 {
+					t.Errorf("Batch(%d, %d) job %d visited %d times", tt.n, tt.workers, i, v)
+				}
+			}
+		})
+	}
+}